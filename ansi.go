@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"sync"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Escape sequences of the terminal
+const (
+	ansiEnter      = "\x1b[?1049h\x1b[2J"
+	ansiLeave      = "\x1b[0m\x1b[?25h\x1b[?1049l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHideCursor = "\x1b[?25l"
+	ansiCtrlC      = 0x03
+	ansiBackspace  = 0x7f
+)
+
+// ansiScreen draws the game with the escape sequences of the terminal, e.g. on the PTY of an SSH session,
+// and reads the keys from the same terminal.
+type ansiScreen struct {
+	grid
+	mu      sync.Mutex
+	out     *bufio.Writer
+	keys    chan event
+	cursor  point
+	visible bool
+}
+
+// Takes over the terminal, and starts reading the keys. See leave.
+func newANSIScreen(width, height int, in io.Reader, out io.Writer) *ansiScreen {
+	as := &ansiScreen{
+		grid: newGrid(width, height),
+		out:  bufio.NewWriter(out),
+		keys: make(chan event, 64),
+	}
+	as.out.WriteString(ansiEnter)
+	go as.read(in)
+	return as
+}
+
+// Give the terminal back as it was.
+func (as *ansiScreen) leave() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.out.WriteString(ansiLeave)
+	return as.out.Flush()
+}
+
+// Draw the cells changed since the last flush.
+func (as *ansiScreen) flush() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.out.WriteString(ansiHideCursor)
+	as.changes(func(x, y int, c termbox.Cell) {
+		as.out.WriteString(moveTo(x, y) + sgr(c.Fg, c.Bg) + string(c.Ch))
+	})
+	if as.visible {
+		as.out.WriteString(moveTo(as.cursor.x, as.cursor.y) + ansiShowCursor)
+	}
+	return as.out.Flush()
+}
+
+func (as *ansiScreen) setCursor(x, y int) {
+	as.cursor, as.visible = point{x, y}, true
+}
+func (as *ansiScreen) hideCursor() {
+	as.visible = false
+}
+
+// Returns the next key, or q when the terminal is closed.
+func (as *ansiScreen) pollEvent() event {
+	if ev, ok := <-as.keys; ok {
+		return ev
+	}
+	return quitEvent
+}
+
+// Read the keys typed on the terminal like termbox.
+func (as *ansiScreen) read(in io.Reader) {
+	defer close(as.keys)
+	r := bufio.NewReader(in)
+	for {
+		ch, _, err := r.ReadRune()
+		if err != nil {
+			return
+		}
+		ev := termbox.Event{Type: termbox.EventKey}
+		switch ch {
+		case '\r', '\n':
+			ev.Key = termbox.KeyEnter
+		case ' ':
+			ev.Key = termbox.KeySpace
+		case ansiCtrlC:
+			ev.Ch = 'q'
+		case ansiBackspace:
+			ev.Key = termbox.KeyBackspace2
+		case chEsc:
+			ev.Key = termbox.KeyEsc
+			// The arrow keys are sent as ESC [ A to D
+			if r.Buffered() >= 2 {
+				if b, _ := r.Peek(2); b[0] == '[' && b[1] >= 'A' && b[1] <= 'D' {
+					r.Discard(2)
+					ev.Key = []termbox.Key{termbox.KeyArrowUp, termbox.KeyArrowDown, termbox.KeyArrowRight, termbox.KeyArrowLeft}[b[1]-'A']
+				}
+			}
+		default:
+			ev.Ch = ch
+		}
+		as.keys <- event{Event: ev}
+	}
+}
+
+func moveTo(x, y int) string {
+	return "\x1b[" + strconv.Itoa(y+1) + ";" + strconv.Itoa(x+1) + "H"
+}
+
+// Returns the escape sequence of the colors and the attributes of a cell.
+func sgr(fg, bg termbox.Attribute) string {
+	seq := "\x1b[0"
+	if fg&termbox.AttrBold != 0 {
+		seq += ";1"
+	}
+	if fg&termbox.AttrUnderline != 0 {
+		seq += ";4"
+	}
+	if fg&termbox.AttrReverse != 0 {
+		seq += ";7"
+	}
+	return seq + ansiColor(fg, 30) + ansiColor(bg, 40) + "m"
+}
+
+// Returns the parameter of the color, e.g. ";32" for the green foreground.
+func ansiColor(a termbox.Attribute, base int) string {
+	c := int(a & 0x1ff)
+	switch {
+	case c >= int(termbox.ColorBlack) && c <= int(termbox.ColorWhite):
+		return ";" + strconv.Itoa(base+c-int(termbox.ColorBlack))
+	case c >= int(termbox.ColorDarkGray) && c <= int(termbox.ColorLightGray):
+		// The bright colors
+		return ";" + strconv.Itoa(base+60+c-int(termbox.ColorDarkGray))
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestANSIScreenRead(t *testing.T) {
+	cases := map[string]struct {
+		in  string
+		key termbox.Key
+		ch  rune
+	}{
+		"char":       {"j", 0, 'j'},
+		"enter":      {"\r", termbox.KeyEnter, 0},
+		"space":      {" ", termbox.KeySpace, 0},
+		"ctrl-c":     {"\x03", 0, 'q'},
+		"backspace":  {"\x7f", termbox.KeyBackspace2, 0},
+		"escape":     {"\x1b", termbox.KeyEsc, 0},
+		"arrow up":   {"\x1b[A", termbox.KeyArrowUp, 0},
+		"arrow left": {"\x1b[D", termbox.KeyArrowLeft, 0},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			as := newANSIScreen(4, 3, strings.NewReader(tt.in), &strings.Builder{})
+			ev := as.pollEvent()
+			if ev.Key != tt.key || ev.Ch != tt.ch {
+				t.Errorf("expected %d %q but %d %q", tt.key, tt.ch, ev.Key, ev.Ch)
+			}
+			// The terminal is closed after the keys
+			if ev := as.pollEvent(); ev != quitEvent {
+				t.Errorf("expected %+v but %+v", quitEvent, ev)
+			}
+		})
+	}
+}
+
+func TestANSIScreenFlush(t *testing.T) {
+	out := &strings.Builder{}
+	as := newANSIScreen(4, 3, strings.NewReader(""), out)
+	as.setCell(2, 1, chApple, termbox.ColorGreen|termbox.AttrBold, termbox.ColorBlack)
+	as.setCursor(1, 0)
+	if err := as.flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := ansiEnter + ansiHideCursor + "\x1b[2;3H\x1b[0;1;32;40mo" + "\x1b[1;2H" + ansiShowCursor
+	if out.String() != expected {
+		t.Errorf("expected %q but %q", expected, out.String())
+	}
+
+	// Only the changes are drawn
+	out.Reset()
+	as.hideCursor()
+	if err := as.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != ansiHideCursor {
+		t.Errorf("expected %q but %q", ansiHideCursor, out.String())
+	}
+}
+
+func TestSGR(t *testing.T) {
+	cases := map[string]struct {
+		fg       termbox.Attribute
+		bg       termbox.Attribute
+		expected string
+	}{
+		"colors":    {termbox.ColorRed, termbox.ColorBlue, "\x1b[0;31;44m"},
+		"default":   {termbox.ColorDefault, termbox.ColorDefault, "\x1b[0m"},
+		"bright":    {termbox.ColorDarkGray, termbox.ColorLightGray, "\x1b[0;90;107m"},
+		"attribute": {termbox.ColorWhite | termbox.AttrUnderline | termbox.AttrReverse, termbox.ColorBlack, "\x1b[0;4;7;37;40m"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if actual := sgr(tt.fg, tt.bg); actual != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -47,7 +47,7 @@ func createWindow(b *buffer) *window {
 }
 
 func (w *window) show(b *buffer) error {
-	if err := termbox.Clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
+	if err := screen.clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
 		return err
 	}
 	maxDigit := getDigit(len(b.lines))
@@ -56,7 +56,7 @@ func (w *window) show(b *buffer) error {
 		linenums := makeLineNum(y+1, maxDigit, b.offset)
 		t := append(linenums, l.text...)
 		for x, r := range t {
-			termbox.SetCell(x, y, r, termbox.ColorWhite, termbox.ColorBlack)
+			screen.setCell(x, y, r, termbox.ColorWhite, termbox.ColorBlack)
 		}
 	}
 	return nil
@@ -88,29 +88,50 @@ func getOffset(linenum int) int {
 	return getDigit(linenum) + 1
 }
 
-func switchScene(fileName string) error {
-	termbox.HideCursor()
+// Show the scene with the messages under it, e.g. the time of the stage.
+func switchScene(fileName string, messages ...string) error {
+	if _, err := drawScene(fileName, messages...); err != nil {
+		return err
+	}
+	if err := screen.flush(); err != nil {
+		return err
+	}
+	if len(messages) > 0 {
+		// Give time to read the messages
+		time.Sleep(2 * time.Second)
+		return nil
+	}
+	time.Sleep(750 * time.Millisecond)
+	return nil
+}
+
+// Draw the scene and the messages under it. Returns the line under them.
+func drawScene(fileName string, messages ...string) (int, error) {
+	screen.hideCursor()
 	f, err := static.ReadFile(fileName)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	b := createBuffer(bytes.NewReader(f))
 	w := createWindow(b)
 
-	if err = termbox.Clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
-		return err
+	if err = screen.clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
+		return 0, err
 	}
 	for y, l := range w.lines {
 		for x, r := range l.text {
-			termbox.SetCell(x, y, r, termbox.ColorYellow, termbox.ColorBlack)
+			screen.setCell(x, y, r, termbox.ColorYellow, termbox.ColorBlack)
 		}
 	}
-	if err = termbox.Flush(); err != nil {
-		return err
+	y := len(w.lines) + 1
+	for _, m := range messages {
+		for x, r := range []rune(m) {
+			screen.setCell(x+3, y, r, termbox.ColorWhite, termbox.ColorBlack)
+		}
+		y++
 	}
-	time.Sleep(750 * time.Millisecond)
-	return nil
+	return y, nil
 }
 
 func isCharBoundary(x, y int) bool {
@@ -120,7 +141,20 @@ func isCharObstacle(x, y int) bool {
 	return isChar(x, y, chObstacle1) || isChar(x, y, chObstacle2) || isChar(x, y, chObstacle3)
 }
 func isCharWall(x, y int) bool {
-	return isCharObstacle(x, y) || isCharBoundary(x, y)
+	return isCharObstacle(x, y) || isCharBoundary(x, y) || isCharDoor(x, y)
+}
+func isCharDoor(x, y int) bool {
+	return isChar(x, y, chDoor)
+}
+func isCharKey(x, y int) bool {
+	return isChar(x, y, chKey)
+}
+func isCharTeleporter(x, y int) bool {
+	return isChar(x, y, chTeleporter)
+}
+func isCharOneWay(x, y int) bool {
+	dx, dy := oneWayDirection(getCell(x, y).Ch)
+	return dx != 0 || dy != 0
 }
 func isCharPlayer(x, y int) bool {
 	return isChar(x, y, chPlayer)
@@ -131,8 +165,15 @@ func isCharHunter(x, y int) bool {
 func isCharGhost(x, y int) bool {
 	return isChar(x, y, chGhost)
 }
+func isCharPatrol(x, y int) bool {
+	return isChar(x, y, chPatrol)
+}
 func isCharEnemy(x, y int) bool {
-	return isCharHunter(x, y) || isCharGhost(x, y)
+	return isCharHunter(x, y) || isCharGhost(x, y) || isCharPatrol(x, y)
+}
+func isCharWaypoint(x, y int) bool {
+	r := getCell(x, y).Ch
+	return r >= '1' && r <= '9'
 }
 func isCharSpace(x, y int) bool {
 	return isChar(x, y, chSpace)
@@ -143,8 +184,19 @@ func isCharApple(x, y int) bool {
 func isCharPoison(x, y int) bool {
 	return isChar(x, y, chPoison)
 }
+func isCharPellet(x, y int) bool {
+	return isChar(x, y, chPellet)
+}
+func isCharPowerUp(x, y int) bool {
+	return isChar(x, y, chFreeze) || isChar(x, y, chSlow) || isChar(x, y, chShield) || isChar(x, y, chExtraLife)
+}
+func isCharFrightened(x, y int) bool {
+	return isCharEnemy(x, y) && getCell(x, y).Bg == colorFrightened
+}
 func isChar(x, y int, r rune) bool {
-	winWidth, _ := termbox.Size()
-	cell := termbox.CellBuffer()[(winWidth*y)+x]
-	return r == cell.Ch
+	return r == getCell(x, y).Ch
+}
+func getCell(x, y int) termbox.Cell {
+	winWidth, _ := screen.size()
+	return screen.cellBuffer()[(winWidth*y)+x]
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Width of a tab in the source files
+const codeTabWidth = 4
+
+var codeValidationError = errors.New("Code Validation Error")
+
+// Keywords of Go become walls of the stage by default
+var goKeywords = []string{
+	"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+	"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+	"select", "struct", "switch", "type", "var",
+}
+
+var codeToken = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// code turns a source file into a stage.
+// The characters become apples, and the chosen tokens become walls or poison.
+type code struct {
+	filePath string
+	// Line of the file at the top of the stage
+	line   int
+	walls  []string
+	poison []string
+}
+
+func newCode(filePath string) code {
+	return code{
+		filePath: filePath,
+		line:     1,
+		walls:    goKeywords,
+		poison:   []string{"nil", "panic"},
+	}
+}
+
+// Returns the stage made from the file.
+func (c code) stage() (stage, error) {
+	src, err := os.ReadFile(c.filePath)
+	if err != nil {
+		return stage{}, err
+	}
+	b, err := c.convert(src)
+	if err != nil {
+		return stage{}, err
+	}
+	s := stage{
+		level:         1,
+		mapPath:       c.filePath,
+		mapData:       b,
+		hunterBuilder: newEnemyBuilder().defaultHunter().speed(0.8),
+		ghostBuilder:  newEnemyBuilder().defaultGhost(),
+		gameSpeed:     1000 * time.Millisecond,
+	}
+	return s, nil
+}
+
+// Converts the source into a stage map. The lines that don't fit in a stage are cut off.
+func (c code) convert(src []byte) ([]byte, error) {
+	tokens := map[string]rune{}
+	for _, t := range c.walls {
+		tokens[t] = chObstacle1
+	}
+	for _, t := range c.poison {
+		tokens[t] = chPoison
+	}
+	maxWidth, maxHeight := maxStageMapWidth-2, maxStageMapHeight-2
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for lineNo := 1; scanner.Scan() && len(lines) < maxHeight; lineNo++ {
+		if lineNo < c.line {
+			continue
+		}
+		text := []rune(strings.TrimRight(strings.ReplaceAll(scanner.Text(), "\t", strings.Repeat(" ", codeTabWidth)), " "))
+		if len(text) > maxWidth {
+			text = text[:maxWidth]
+		}
+		l := make([]rune, len(text))
+		for i, r := range text {
+			l[i] = chSpace
+			if r != chSpace {
+				l[i] = chApple
+			}
+		}
+		for _, loc := range codeToken.FindAllStringIndex(string(text), -1) {
+			// The indexes of the runes of the token
+			from, to := len([]rune(string(text)[:loc[0]])), len([]rune(string(text)[:loc[1]]))
+			if r, ok := tokens[string(text)[loc[0]:loc[1]]]; ok {
+				for i := from; i < to; i++ {
+					l[i] = r
+				}
+			}
+		}
+		lines = append(lines, string(l))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Blank lines at the bottom are cut off
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		err := errors.New(c.filePath + "; No code from line " + strconv.Itoa(c.line) + ";")
+		return nil, fmt.Errorf("%w: %+v", codeValidationError, err)
+	}
+
+	width := 0
+	for _, l := range lines {
+		if len(l) > width {
+			width = len(l)
+		}
+	}
+	cells := make([][]rune, len(lines)+2)
+	for y := range cells {
+		cells[y] = []rune(strings.Repeat(string(chBoundary), width+2))
+		if y > 0 && y < len(cells)-1 {
+			copy(cells[y][1:], []rune(lines[y-1]+strings.Repeat(" ", width-len(lines[y-1]))))
+		}
+	}
+
+	// The player starts on the first character of the code
+	start, found := point{}, false
+	for y := 1; y < len(cells)-1 && !found; y++ {
+		for x := 1; x < len(cells[y])-1 && !found; x++ {
+			if cells[y][x] == chApple {
+				start, found = point{x, y}, true
+			}
+		}
+	}
+	if !found {
+		err := errors.New(c.filePath + "; No characters to eat from line " + strconv.Itoa(c.line) + ";")
+		return nil, fmt.Errorf("%w: %+v", codeValidationError, err)
+	}
+
+	// Apples behind walls or poison are removed, so that the stage can be cleared
+	open := make([][]rune, len(cells))
+	for y := range cells {
+		open[y] = []rune(strings.ReplaceAll(string(cells[y]), string(chApple), string(chSpace)))
+	}
+	distances := reachableCells(open, start)
+	far := []point{}
+	for y := range cells {
+		for x, r := range cells[y] {
+			d, ok := distances[point{x, y}]
+			if r == chApple && !ok {
+				cells[y][x] = chSpace
+			}
+			if r == chSpace && ok && d > 0 {
+				far = append(far, point{x, y})
+			}
+		}
+	}
+	cells[start.y][start.x] = chPlayer
+
+	// A hunter starts at the farthest space from the player, and a ghost joins on longer code
+	enemies := []rune{chHunter}
+	if len(lines) > 10 {
+		enemies = append(enemies, chGhost)
+	}
+	for _, r := range enemies {
+		i := -1
+		for j, pt := range far {
+			if i < 0 || distances[pt] > distances[far[i]] {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+		cells[far[i].y][far[i].x] = r
+		far = append(far[:i], far[i+1:]...)
+	}
+
+	rows := make([]string, len(cells))
+	for y := range cells {
+		rows[y] = string(cells[y])
+	}
+	b := []byte(strings.Join(rows, "\n"))
+	if err := validateStage(b, c.filePath); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertCode(t *testing.T) {
+	cases := map[string]struct {
+		src      string
+		line     int
+		expected []string
+	}{
+		"keywords and poison": {
+			"func f() {\n\treturn nil\n}",
+			1,
+			[]string{
+				"++++++++++++++++",
+				"+---- Poo o    +",
+				"+H   ------ XXX+",
+				"+o             +",
+				"++++++++++++++++",
+			},
+		},
+		"apples behind walls": {
+			"x\nreturn\ngo(a)if\nreturn",
+			1,
+			[]string{
+				"+++++++++",
+				"+P      +",
+				"+------H+",
+				"+--   --+",
+				"+------ +",
+				"+++++++++",
+			},
+		},
+		"from the line": {
+			"package main\n\nvar x = 1\n",
+			3,
+			[]string{
+				"+++++++++++",
+				"+--- P oHo+",
+				"+++++++++++",
+			},
+		},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c := newCode(name)
+			c.line = tt.line
+			b, err := c.convert([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := strings.Join(tt.expected, "\n")
+			if string(b) != expected {
+				t.Errorf("expected\n%s\nbut\n%s", expected, b)
+			}
+		})
+	}
+}
+
+// Test long code is cut off to fit in a stage.
+func TestConvertLongCode(t *testing.T) {
+	src := strings.Repeat(strings.Repeat("a ", 40)+"\n", 30)
+	b, err := newCode("long").convert([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateStage(b, "long"); err != nil {
+		t.Error(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if len(lines) != maxStageMapHeight || len(lines[0]) != maxStageMapWidth {
+		t.Errorf("expected %dx%d but %dx%d", maxStageMapWidth, maxStageMapHeight, len(lines[0]), len(lines))
+	}
+}
+
+func TestConvertCodeError(t *testing.T) {
+	cases := map[string]struct {
+		src  string
+		line int
+	}{
+		"empty":             {"", 1},
+		"after the end":     {"x := 1\n", 2},
+		"nothing to eat":    {"return\n", 1},
+		"only blank lines":  {"\n\n\t\n", 1},
+		"walls and poisons": {"if nil {\n}", 3},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c := newCode(name)
+			c.line = tt.line
+			if _, err := c.convert([]byte(tt.src)); !errors.Is(err, codeValidationError) {
+				t.Errorf("expected %v but %v", codeValidationError, err)
+			}
+		})
+	}
+}
+
+func TestCodeStage(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(filePath, []byte("package main\n\nfunc main() {\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newCode(filePath).stage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.readMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), string(chPlayer)) {
+		t.Errorf("expected the player in\n%s", b)
+	}
+	if _, err := newCode(filepath.Join(t.TempDir(), "none.go")).stage(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v but %v", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import "sync"
+
+// trail is the cells the player has passed through since the last tick of enemies.
+// The player moves in another goroutine, so it is guarded by a mutex.
+type trail struct {
+	mu    sync.Mutex
+	cells []point
+}
+
+func newTrail(x, y int) *trail {
+	return &trail{cells: []point{{x, y}}}
+}
+
+// Record a cell the player has reached.
+func (t *trail) add(x, y int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cells = append(t.cells, point{x, y})
+}
+
+// Record the cells a jump of the player passes over, and the cell it lands on.
+func (t *trail) jump(from point, x, y int) {
+	for _, c := range sweep(from, point{x, y}) {
+		t.add(c.x, c.y)
+	}
+}
+
+// Returns the cells passed since the last call, ending with the current cell,
+// and starts a new trail from the current cell.
+func (t *trail) take(x, y int) []point {
+	current := point{x, y}
+	if t == nil {
+		return []point{current}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cells := t.cells
+	if len(cells) == 0 || cells[len(cells)-1] != current {
+		cells = append(cells, current)
+	}
+	t.cells = []point{current}
+	return cells
+}
+
+// Returns the cells a jump passes from a cell to another, ending with the destination.
+// The jump goes along the column first and then along the row, like the cursor moves to the line and then to the word.
+func sweep(from, to point) []point {
+	cells := []point{}
+	dx, dy := direction(0, to.y-from.y)
+	for c := from; c.y != to.y; {
+		c.x, c.y = c.x+dx, c.y+dy
+		cells = append(cells, c)
+	}
+	dx, dy = direction(to.x-from.x, 0)
+	for c := (point{from.x, to.y}); c.x != to.x; {
+		c.x, c.y = c.x+dx, c.y+dy
+		cells = append(cells, c)
+	}
+	if len(cells) == 0 {
+		cells = append(cells, to)
+	}
+	return cells
+}
+
+// Whether the enemy that moved from (fromX, fromY) in this tick collides with the player.
+// The enemy moving into or jumping over the player's cell is a collision, and so is the player passing the cell the enemy left,
+// e.g. when they swap their cells or the player walks through or jumps over the enemy between ticks.
+// The path is the trail of the player ending with the current cell.
+func hasCollided(e iEnemy, fromX, fromY int, path []point) bool {
+	if len(path) == 0 {
+		return false
+	}
+	x, y := e.getPosition()
+	for _, c := range sweep(point{fromX, fromY}, point{x, y}) {
+		if c == path[len(path)-1] {
+			return true
+		}
+	}
+	for _, c := range path {
+		if c == (point{fromX, fromY}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHasCollided(t *testing.T) {
+	cases := map[string]struct {
+		from     point
+		to       point
+		path     []point
+		expected bool
+	}{
+		"move into the player":              {point{2, 2}, point{3, 2}, []point{{3, 2}}, true},
+		"swap cells":                        {point{2, 2}, point{3, 2}, []point{{3, 2}, {2, 2}}, true},
+		"player walks through the enemy":    {point{2, 2}, point{2, 2}, []point{{1, 2}, {2, 2}, {3, 2}}, true},
+		"pass each other on the same line":  {point{3, 2}, point{2, 2}, []point{{1, 2}, {2, 2}, {3, 2}, {4, 2}}, true},
+		"move into a cell the player left":  {point{2, 2}, point{3, 2}, []point{{3, 2}, {4, 2}}, false},
+		"player jumps over the enemy":       {point{2, 2}, point{2, 2}, []point{{1, 2}, {2, 2}, {3, 2}, {4, 2}, {5, 2}}, true},
+		"enemy jumps over the player":       {point{1, 2}, point{5, 2}, []point{{3, 2}}, true},
+		"enemy jumps to another line":       {point{1, 2}, point{5, 4}, []point{{1, 3}}, true},
+		"enemy jumps past the player":       {point{1, 2}, point{5, 2}, []point{{3, 3}}, false},
+		"move away from the player":         {point{2, 2}, point{1, 2}, []point{{3, 2}}, false},
+		"player is next to the enemy":       {point{2, 2}, point{2, 2}, []point{{2, 3}}, false},
+		"move into the cell the player was": {point{2, 1}, point{2, 2}, []point{{2, 2}, {2, 3}}, false},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			e := &enemy{}
+			e.setPosition(tt.to.x, tt.to.y)
+			if actual := hasCollided(e, tt.from.x, tt.from.y, tt.path); actual != tt.expected {
+				t.Errorf("expected %t but %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	cases := map[string]struct {
+		from     point
+		to       point
+		expected []point
+	}{
+		"step":            {point{2, 2}, point{3, 2}, []point{{3, 2}}},
+		"stay":            {point{2, 2}, point{2, 2}, []point{{2, 2}}},
+		"along the row":   {point{5, 2}, point{2, 2}, []point{{4, 2}, {3, 2}, {2, 2}}},
+		"to another line": {point{2, 3}, point{4, 1}, []point{{2, 2}, {2, 1}, {3, 1}, {4, 1}}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			actual := sweep(tt.from, tt.to)
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("expected %v but %v", tt.expected, actual)
+			}
+			for i := range actual {
+				if actual[i] != tt.expected[i] {
+					t.Errorf("expected %v but %v", tt.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestTrail(t *testing.T) {
+	tr := newTrail(1, 1)
+	tr.add(2, 1)
+	tr.add(3, 1)
+	if path := tr.take(3, 1); len(path) != 3 || path[0] != (point{1, 1}) || path[2] != (point{3, 1}) {
+		t.Errorf("unexpected path %v", path)
+	}
+	// The next trail starts from the current cell
+	if path := tr.take(3, 1); len(path) != 1 || path[0] != (point{3, 1}) {
+		t.Errorf("unexpected path %v", path)
+	}
+	// The position set without walking is also a part of the path
+	if path := tr.take(5, 1); len(path) != 2 || path[1] != (point{5, 1}) {
+		t.Errorf("unexpected path %v", path)
+	}
+	var empty *trail
+	if path := empty.take(1, 1); len(path) != 1 {
+		t.Errorf("unexpected path %v", path)
+	}
+}
+
+// Test the player and an enemy can't pass through each other between ticks.
+func TestPassThrough(t *testing.T) {
+	cases := map[string]struct {
+		energized     bool
+		jump          bool
+		expectedState int
+		expectedBonus int
+	}{
+		"walk through a chasing enemy":    {false, false, lose, 0},
+		"walk through a frightened enemy": {true, false, continuing, captureBonus},
+		"jump over a chasing enemy":       {false, true, lose, 0},
+		"jump over a frightened enemy":    {true, true, continuing, captureBonus},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter().speed(0))
+			if err != nil {
+				t.Error(err)
+			}
+			e := s.enemies[0]
+			x, y := e.getPosition()
+			p.state = continuing
+			p.energized = tt.energized
+			// The player moves in its own goroutine while the enemy waits for the next tick,
+			// so the cells are recorded without the hit detection of the walk
+			p.x, p.y = x-1, y
+			p.trail = newTrail(p.x, p.y)
+			if tt.jump {
+				p.trail.jump(point{p.x, p.y}, x+1, y)
+			} else {
+				p.trail.add(x, y)
+				p.trail.add(x+1, y)
+			}
+			p.x, p.y = x+1, y
+			if err := s.control(players{p}); err != nil {
+				t.Error(err)
+			}
+			if p.state != tt.expectedState || p.bonus != tt.expectedBonus {
+				t.Errorf("expected %d %d but %d %d", tt.expectedState, tt.expectedBonus, p.state, p.bonus)
+			}
+		})
+	}
+}
+
+// Test the jumps of the player over an enemy are caught on the next tick.
+func TestJumpOver(t *testing.T) {
+	cases := map[string]struct {
+		motion        rune
+		startX        int
+		expectedState int
+	}{
+		"to the end of the line":       {'$', -2, lose},
+		"to the beginning of the line": {'0', 2, lose},
+		"away from the enemy":          {'$', 1, continuing},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter().speed(0))
+			if err != nil {
+				t.Error(err)
+			}
+			x, y := s.enemies[0].getPosition()
+			p.state = continuing
+			p.x, p.y = x+tt.startX, y
+			p.trail = newTrail(p.x, p.y)
+			p.action(tt.motion, s)
+			if err := s.control(players{p}); err != nil {
+				t.Error(err)
+			}
+			if p.state != tt.expectedState {
+				t.Errorf("expected %d but %d", tt.expectedState, p.state)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Levels of the stages of the daily challenge
+const (
+	minDailyLevel = 1
+	maxDailyLevel = 8
+)
+
+// Seed of the daily challenge. Everyone gets the same stage on the same date, e.g. 20261019.
+func dailySeed(date time.Time) int64 {
+	y, m, d := date.Date()
+	return int64(y*10000 + int(m)*100 + d)
+}
+
+// Returns the stage of the date. The level of the day is also chosen by the seed.
+func dailyStage(date time.Time) (stage, error) {
+	seed := dailySeed(date)
+	level := minDailyLevel + rand.New(rand.NewSource(seed)).Intn(maxDailyLevel-minDailyLevel+1)
+	return endlessStage(seed, level)
+}
+
+// dailyResult is the result of the daily challenge shared after the game.
+type dailyResult struct {
+	date       time.Time
+	level      int
+	cleared    bool
+	score      int
+	target     int
+	keystrokes int
+	playTime   time.Duration
+	lives      int
+}
+
+// Add the attempt of the stage to the result.
+func (r *dailyResult) add(p *player) {
+	r.keystrokes += p.keystrokes
+	r.playTime += p.playTime
+	r.score, r.target = p.score, p.targetScore
+	r.cleared = p.state == win
+}
+
+// Returns the text to share, e.g.
+//
+//	PacVim daily 2026-10-19 (level 3)
+//	Cleared: 42/42 apples
+//	Keystrokes: 57, Time: 63.2s, Lives: 2
+func (r dailyResult) summary() string {
+	lines := []string{"PacVim daily " + r.date.Format("2006-01-02") + " (level " + strconv.Itoa(r.level) + ")"}
+	result := "Failed"
+	if r.cleared {
+		result = "Cleared"
+	}
+	lines = append(lines, result+": "+strconv.Itoa(r.score)+"/"+strconv.Itoa(r.target)+" apples")
+	lives := r.lives
+	if lives < 0 {
+		lives = 0
+	}
+	lines = append(lines, "Keystrokes: "+strconv.Itoa(r.keystrokes)+", Time: "+formatTime(r.playTime)+", Lives: "+strconv.Itoa(lives))
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDailySeed(t *testing.T) {
+	cases := map[string]struct {
+		date     time.Time
+		expected int64
+	}{
+		"morning":  {time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local), 20261019},
+		"midnight": {time.Date(2026, 10, 19, 23, 59, 59, 0, time.Local), 20261019},
+		"next day": {time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local), 20261020},
+		"new year": {time.Date(2027, 1, 1, 12, 0, 0, 0, time.Local), 20270101},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if seed := dailySeed(tt.date); seed != tt.expected {
+				t.Errorf("expected %d but %d", tt.expected, seed)
+			}
+		})
+	}
+}
+
+// Test everyone gets the same stage on the same date.
+func TestDailyStage(t *testing.T) {
+	morning, err := dailyStage(time.Date(2026, 10, 19, 8, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, err := dailyStage(time.Date(2026, 10, 19, 22, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(morning.mapData, night.mapData) || morning.level != night.level {
+		t.Errorf("expected the same stage but\n%s\n%s", morning.mapData, night.mapData)
+	}
+	if morning.level < minDailyLevel || morning.level > maxDailyLevel {
+		t.Errorf("expected the level between %d and %d but %d", minDailyLevel, maxDailyLevel, morning.level)
+	}
+	next, err := dailyStage(time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(morning.mapData, next.mapData) {
+		t.Error("expected another stage on the next day")
+	}
+}
+
+func TestDailyResult(t *testing.T) {
+	r := dailyResult{date: time.Date(2026, 10, 19, 8, 0, 0, 0, time.Local), level: 3}
+	// Lost once, then cleared
+	r.add(&player{state: lose, score: 10, targetScore: 42, keystrokes: 20, playTime: 15 * time.Second})
+	r.add(&player{state: win, score: 42, targetScore: 42, keystrokes: 37, playTime: 48200 * time.Millisecond})
+	r.lives = 1
+	expected := "PacVim daily 2026-10-19 (level 3)\nCleared: 42/42 apples\nKeystrokes: 57, Time: 63.2s, Lives: 1\n"
+	if s := r.summary(); s != expected {
+		t.Errorf("expected %q but %q", expected, s)
+	}
+	// Lost all the lives
+	r.add(&player{state: lose, score: 5, targetScore: 42, keystrokes: 3, playTime: time.Second})
+	r.lives = -1
+	expected = "PacVim daily 2026-10-19 (level 3)\nFailed: 5/42 apples\nKeystrokes: 60, Time: 64.2s, Lives: 0\n"
+	if s := r.summary(); s != expected {
+		t.Errorf("expected %q but %q", expected, s)
+	}
+}
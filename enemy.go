@@ -8,23 +8,78 @@ import (
 	termbox "github.com/nsf/termbox-go"
 )
 
+// Modes of enemies
+const (
+	// Chase the player with its own strategy
+	chase int = iota
+	// Go back to its home
+	scatter
+	// Run away from the player after the player has eaten a power pellet
+	frightened
+)
+
+// Lifecycle of enemies
+const (
+	// On the board and chasing the player
+	active int = iota
+	// Captured by the player
+	eaten
+	// Going back home off the board
+	returning
+	// Waiting at home to respawn
+	waiting
+)
+
+const (
+	colorFrightened = termbox.ColorBlue
+	// Points for capturing a frightened enemy
+	captureBonus = 10
+	// Number of ticks an enemy waits at home before it respawns
+	defaultRespawnDelay = 5
+	// Progress needed to move a cell (in thousandths of a cell)
+	stepCost = 1000
+)
+
 type iEnemy interface {
 	getPosition() (x, y int)
 	setPosition(x, y int)
+	setHome(x, y int)
+	getHome() (int, int)
+	wait(x, y int)
 	getDisplayFormat() (rune, termbox.Attribute)
+	setMode(mode int)
+	frighten(n int)
+	update(p *player) bool
 	think(p *player) (int, int)
-	move(x, y int)
+	bind(s *stage)
+	countDown()
+	accelerate(d time.Duration, multiplier float64)
+	isReady() bool
+	move(x, y int) bool
 	hasCaptured(p *player)
+	capture(p *player)
+	putUnder(x, y int, c termbox.Cell) bool
+	failed() error
 	eval(p *player, x, y int) float64
 }
 type enemy struct {
-	x            int
-	y            int
-	char         rune
-	color        termbox.Attribute
-	waitingTime  int
-	oneActionInN int
-	canMove      func(int, int) bool
+	x     int
+	y     int
+	homeX int
+	homeY int
+	char  rune
+	color termbox.Attribute
+	// Cells per second
+	speed float64
+	// A cell is moved each time the progress reaches stepCost
+	progress       int
+	mode           int
+	frightenedTime int
+	lifecycle      int
+	respawnDelay   int
+	respawnTime    int
+	canMove        func(int, int) bool
+	isOpaque       func(int, int) bool
 	strategy
 	underRune
 }
@@ -38,9 +93,116 @@ type ghost struct {
 type strategy interface {
 	eval(p *player, x, y int) float64
 }
+
+// Strategies that need to know the enemy they drive or the stage (e.g. the other enemies).
+// bind returns the strategy for the enemy so that each enemy has its own state.
+type boundStrategy interface {
+	strategy
+	bind(e *enemy, s *stage) strategy
+}
+
+// Strategies that prepare once per move before eval is called for each direction.
+type plannedStrategy interface {
+	strategy
+	plan(p *player)
+}
+
+// Strategies that can fail while the game is running, e.g. scripts.
+type fallibleStrategy interface {
+	strategy
+	failed() error
+}
+
+// Strategies that move the enemy to a cell that is not next to it.
+// jump returns false to step to a neighbouring cell as usual.
+type jumpStrategy interface {
+	strategy
+	jump(p *player) (int, int, bool)
+}
+
+// Strategies that tell whether the enemy has spotted the player.
+type alertStrategy interface {
+	strategy
+	alerted() bool
+}
+
 type assault struct{}
 type tricky struct{}
 
+// flee runs away from the player.
+type flee struct{}
+
+// retreat heads for a fixed cell regardless of the player.
+type retreat struct {
+	x int
+	y int
+}
+type pathfinding struct {
+	canMove  func(int, int) bool
+	distance map[point]int
+}
+
+// ambusher heads for the cells ahead of the direction the player is moving.
+type ambusher struct{}
+
+// flanker heads for the cell on the opposite side of the player from its partner,
+// so that the player is caught between them.
+type flanker struct {
+	// The partner is the first other enemy displayed with this character (any enemy if 0)
+	partnerChar rune
+	partner     iEnemy
+}
+
+// patrol goes round the waypoints of the stage in order, regardless of the player.
+type patrol struct {
+	self     *enemy
+	route    []point
+	next     int
+	distance map[point]int
+}
+
+// lineOfSight wanders until it sees the player along a row or a column,
+// then chases the player with its own strategy.
+// After losing sight of the player, it goes to where the player was last seen.
+type lineOfSight struct {
+	chase    strategy
+	self     *enemy
+	spotted  bool
+	tracking bool
+	lastX    int
+	lastY    int
+}
+
+// vimMotion steps with its own strategy, and now and then jumps toward the player with a Vim motion.
+// The enemy shows its character a move before the jump, so that the player can anticipate it.
+type vimMotion struct {
+	chase strategy
+	// A motion is tried once in this number of moves (vimMotionInterval if 0)
+	interval int
+	self     *enemy
+	stage    *stage
+	moves    int
+	// The motion announced for the next move
+	motion rune
+}
+
+// shy chases the player, but retreats to its home corner when it gets close.
+type shy struct {
+	self   *enemy
+	corner point
+}
+
+const (
+	// Number of cells ahead of the player the ambusher aims at.
+	ambushDistance = 4
+	// Number of cells ahead of the player the flanker aims at.
+	flankDistance = 2
+	// Distance at which the shy enemy retreats.
+	shyDistance = 8
+	// Number of moves between the Vim motions of an enemy.
+	vimMotionInterval = 4
+)
+
 type underRune struct {
 	char    rune
 	fgColor termbox.Attribute
@@ -54,11 +216,122 @@ func (e *enemy) setPosition(x, y int) {
 	e.x, e.y = x, y
 }
 
+func (e *enemy) setHome(x, y int) {
+	e.homeX, e.homeY = x, y
+}
+func (e *enemy) getHome() (int, int) {
+	return e.homeX, e.homeY
+}
+
+// Put the enemy off the board. It appears at the home when the home is vacant.
+func (e *enemy) wait(x, y int) {
+	e.setHome(x, y)
+	e.setPosition(x, y)
+	e.lifecycle = waiting
+	e.respawnTime = 0
+}
+
 func (e *enemy) getDisplayFormat() (rune, termbox.Attribute) {
+	if e.mode == frightened {
+		return e.char, colorFrightened
+	}
 	return e.char, e.color
 }
 
+func (e *enemy) draw() {
+	char, color := e.getDisplayFormat()
+	fg := color
+	if e.isAlerted() && e.mode != frightened {
+		// Show the character when the enemy has spotted the player
+		fg = termbox.ColorWhite | termbox.AttrBold
+	}
+	screen.setCell(e.x, e.y, char, fg, color)
+}
+
+// Switch between chase and scatter.
+// A frightened enemy keeps running away until the time is up.
+func (e *enemy) setMode(mode int) {
+	if e.mode != frightened {
+		e.mode = mode
+	}
+}
+
+// Frighten the enemy for n moves.
+// Enemies off the board are not frightened.
+func (e *enemy) frighten(n int) {
+	if e.lifecycle != active {
+		return
+	}
+	e.mode = frightened
+	e.frightenedTime = n
+	e.draw()
+}
+
+// Advance the lifecycle of the enemy by one tick.
+// Returns whether the enemy is on the board and can move.
+func (e *enemy) update(p *player) bool {
+	switch e.lifecycle {
+	case eaten:
+		e.lifecycle = returning
+	case returning:
+		if e.x == e.homeX && e.y == e.homeY {
+			e.lifecycle = waiting
+			e.respawnTime = e.respawnDelay
+		} else {
+			e.setPosition(e.stepHome())
+		}
+	case waiting:
+		e.respawnTime--
+		// Wait until the home is vacant
+		if e.respawnTime <= 0 && !isCharEnemy(e.homeX, e.homeY) && !(p.x == e.homeX && p.y == e.homeY) {
+			e.respawn()
+		}
+		// The enemy starts moving from the next tick
+		return false
+	}
+	return e.lifecycle == active
+}
+
+// Returns the next cell on the shortest way home.
+// Like a ghost, the enemy goes through obstacles on the way home.
+func (e *enemy) stepHome() (int, int) {
+	distance := walkableDistance(point{e.homeX, e.homeY}, func(x, y int) bool {
+		return !isCharBoundary(x, y)
+	})
+	x, y := e.x, e.y
+	for _, n := range []point{{e.x, e.y - 1}, {e.x, e.y + 1}, {e.x - 1, e.y}, {e.x + 1, e.y}} {
+		if d, ok := distance[n]; ok && d < distance[point{x, y}] {
+			x, y = n.x, n.y
+		}
+	}
+	return x, y
+}
+
+// Put the enemy back on the board at its home.
+func (e *enemy) respawn() {
+	winWidth, _ := screen.size()
+	cell := screen.cellBuffer()[(winWidth*e.homeY)+e.homeX]
+	e.setPosition(e.homeX, e.homeY)
+	e.underRune = underRune{char: cell.Ch, fgColor: cell.Fg, bgColor: cell.Bg}
+	e.lifecycle = active
+	e.mode = chase
+	e.progress = 0
+	e.draw()
+}
+
 func (e *enemy) think(p *player) (int, int) {
+	if s, ok := e.currentStrategy().(plannedStrategy); ok {
+		alerted := e.isAlerted()
+		s.plan(p)
+		if alerted != e.isAlerted() {
+			e.draw()
+		}
+	}
+	if s, ok := e.currentStrategy().(jumpStrategy); ok {
+		if x, y, ok := s.jump(p); ok {
+			return x, y
+		}
+	}
 	x, y := e.getPosition()
 	// Calculate the evaluation value for movement
 	up := e.eval(p, x, y-1)
@@ -79,36 +352,163 @@ func (e *enemy) think(p *player) (int, int) {
 	}
 }
 
-func (e *enemy) move(x, y int) {
-	e.waitingTime--
-	if e.waitingTime <= 0 && e.canMove(x, y) {
-		winWidth, _ := termbox.Size()
-		// Set the original character in the original cell
-		termbox.SetCell(e.x, e.y, e.underRune.char, e.underRune.fgColor, e.underRune.bgColor)
-		// Retains destination cell information
-		// Because it is necessary to set the original character at the next move
-		cell := termbox.CellBuffer()[(winWidth*y)+x]
-		e.setPosition(x, y)
-		e.underRune.char = cell.Ch
-		e.underRune.fgColor = cell.Fg
-		e.underRune.bgColor = cell.Bg
-		termbox.SetCell(x, y, e.char, e.color, e.color)
-		e.waitingTime = e.oneActionInN
+func (e *enemy) isAlerted() bool {
+	s, ok := e.strategy.(alertStrategy)
+	return ok && s.alerted()
+}
+
+// Whether the enemy can see the player along a row or a column.
+func (e *enemy) canSee(x, y int) bool {
+	isOpaque := e.isOpaque
+	if isOpaque == nil {
+		isOpaque = isCharWall
+	}
+	if e.x != x && e.y != y {
+		return false
+	}
+	dx, dy := direction(x-e.x, y-e.y)
+	for cx, cy := e.x+dx, e.y+dy; cx != x || cy != y; cx, cy = cx+dx, cy+dy {
+		if isOpaque(cx, cy) {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the step to the neighbouring cell goes against a one-way tile.
+// The tile under the enemy is kept in underRune. Jumps are not blocked.
+func (e *enemy) isOneWayBlocked(x, y int) bool {
+	dx, dy := x-e.x, y-e.y
+	if dx*dx+dy*dy != 1 {
+		return false
+	}
+	return isOneWayBlocked(e.underRune.char, getCell(x, y).Ch, dx, dy)
+}
+
+// Returns the error of the strategy that stops the stage.
+func (e *enemy) failed() error {
+	if s, ok := e.strategy.(fallibleStrategy); ok {
+		return s.failed()
+	}
+	return nil
+}
+
+func (e *enemy) bind(s *stage) {
+	if bs, ok := e.strategy.(boundStrategy); ok {
+		e.strategy = bs.bind(e, s)
+	}
+}
+
+// Count down the frightened time by a tick, whether the enemy moves or not.
+func (e *enemy) countDown() {
+	if e.mode != frightened {
+		return
+	}
+	e.frightenedTime--
+	if e.frightenedTime <= 0 {
+		e.mode = chase
+		e.draw()
+	}
+}
+
+// Gain the progress of a tick of the given length.
+// The multiplier is the acceleration of the stage at the moment.
+func (e *enemy) accelerate(d time.Duration, multiplier float64) {
+	if e.mode == frightened {
+		// A frightened enemy moves at half speed
+		multiplier /= 2
 	}
+	e.progress += int(math.Round(e.speed * multiplier * float64(d.Milliseconds())))
+}
+
+// Whether the enemy has enough progress to move a cell.
+func (e *enemy) isReady() bool {
+	return e.lifecycle == active && e.progress >= stepCost
+}
+
+// Returns whether the enemy has moved.
+func (e *enemy) move(x, y int) bool {
+	if e.progress < stepCost {
+		return false
+	}
+	if !e.canMove(x, y) || e.isOneWayBlocked(x, y) {
+		// A blocked enemy doesn't save up moves
+		e.progress = stepCost
+		return false
+	}
+	winWidth, _ := screen.size()
+	// Set the original character in the original cell
+	screen.setCell(e.x, e.y, e.underRune.char, e.underRune.fgColor, e.underRune.bgColor)
+	// Retains destination cell information
+	// Because it is necessary to set the original character at the next move
+	cell := screen.cellBuffer()[(winWidth*y)+x]
+	e.setPosition(x, y)
+	e.underRune.char = cell.Ch
+	e.underRune.fgColor = cell.Fg
+	e.underRune.bgColor = cell.Bg
+	e.draw()
+	e.progress -= stepCost
+	return true
 }
 
 func (e *enemy) hasCaptured(p *player) {
 	if e.x == p.x && e.y == p.y {
+		e.capture(p)
+	}
+}
+
+// The enemy and the player have collided.
+func (e *enemy) capture(p *player) {
+	if e.lifecycle != active {
+		return
+	}
+	if e.mode == frightened {
+		// The player captures the enemy instead
+		p.bonus += captureBonus
+		e.leaveBoard()
+	} else if p.shield {
+		// The shield sends the enemy home, and it is used up
+		p.shield = false
+		e.leaveBoard()
+	} else {
 		p.state = lose
 	}
 }
 
+// Replace the cell the enemy puts back when it moves off, if the enemy is on the board at the cell.
+// Returns whether it is.
+func (e *enemy) putUnder(x, y int, c termbox.Cell) bool {
+	if e.lifecycle != active || e.x != x || e.y != y {
+		return false
+	}
+	e.underRune = underRune{char: c.Ch, fgColor: c.Fg, bgColor: c.Bg}
+	return true
+}
+
+// Take the captured enemy off the board.
+func (e *enemy) leaveBoard() {
+	screen.setCell(e.x, e.y, e.underRune.char, e.underRune.fgColor, e.underRune.bgColor)
+	e.mode = chase
+	e.frightenedTime = 0
+	e.lifecycle = eaten
+}
+
 func (e *enemy) eval(p *player, x, y int) float64 {
-	if !e.canMove(x, y) {
+	if !e.canMove(x, y) || e.isOneWayBlocked(x, y) {
 		// Returns a large enough value if it can't move
 		return 1000
 	}
-	return e.strategy.eval(p, x, y)
+	return e.currentStrategy().eval(p, x, y)
+}
+
+func (e *enemy) currentStrategy() strategy {
+	switch e.mode {
+	case frightened:
+		return &flee{}
+	case scatter:
+		return &retreat{x: e.homeX, y: e.homeY}
+	}
+	return e.strategy
 }
 func (s *assault) eval(p *player, x, y int) float64 {
 	// Distance between two points
@@ -122,6 +522,234 @@ func (s *tricky) eval(p *player, x, y int) float64 {
 	}
 }
 
+func (s *flee) eval(p *player, x, y int) float64 {
+	// The farther from the player, the better
+	return -math.Sqrt(math.Pow(float64(p.y-y), 2) + math.Pow(float64(p.x-x), 2))
+}
+func (s *retreat) eval(p *player, x, y int) float64 {
+	return math.Sqrt(math.Pow(float64(s.y-y), 2) + math.Pow(float64(s.x-x), 2))
+}
+
+func (s *pathfinding) bind(e *enemy, st *stage) strategy {
+	return &pathfinding{canMove: e.canMove}
+}
+func (s *pathfinding) plan(p *player) {
+	// The walkable distance is calculated once per move, not for each direction
+	s.distance = walkableDistance(point{p.x, p.y}, s.canMove)
+}
+func (s *pathfinding) eval(p *player, x, y int) float64 {
+	if s.distance == nil {
+		s.plan(p)
+	}
+	if d, ok := s.distance[point{x, y}]; ok {
+		return float64(d)
+	}
+	// If the player can't be reached, approach in a straight line
+	// The value is larger than any walkable distance, but smaller than the value when it can't move
+	return 500 + math.Sqrt(math.Pow(float64(p.y-y), 2)+math.Pow(float64(p.x-x), 2))
+}
+
+func (s *ambusher) eval(p *player, x, y int) float64 {
+	tx, ty := p.x+p.dirX*ambushDistance, p.y+p.dirY*ambushDistance
+	return math.Sqrt(math.Pow(float64(ty-y), 2) + math.Pow(float64(tx-x), 2))
+}
+
+func (s *flanker) bind(e *enemy, st *stage) strategy {
+	f := &flanker{partnerChar: s.partnerChar}
+	for _, other := range st.enemies {
+		if other == iEnemy(e) {
+			continue
+		}
+		if char, _ := other.getDisplayFormat(); s.partnerChar == 0 || char == s.partnerChar {
+			f.partner = other
+			break
+		}
+	}
+	return f
+}
+func (s *flanker) eval(p *player, x, y int) float64 {
+	tx, ty := p.x+p.dirX*flankDistance, p.y+p.dirY*flankDistance
+	if s.partner != nil {
+		// Double the vector from the partner to the cell ahead of the player
+		px, py := s.partner.getPosition()
+		tx, ty = 2*tx-px, 2*ty-py
+	}
+	return math.Sqrt(math.Pow(float64(ty-y), 2) + math.Pow(float64(tx-x), 2))
+}
+
+func (s *lineOfSight) bind(e *enemy, st *stage) strategy {
+	chase := s.chase
+	if chase == nil {
+		chase = &assault{}
+	}
+	if bs, ok := chase.(boundStrategy); ok {
+		chase = bs.bind(e, st)
+	}
+	return &lineOfSight{chase: chase, self: e}
+}
+func (s *lineOfSight) plan(p *player) {
+	s.spotted = s.self.canSee(p.x, p.y)
+	if s.spotted {
+		s.tracking = true
+		s.lastX, s.lastY = p.x, p.y
+		if ps, ok := s.chase.(plannedStrategy); ok {
+			ps.plan(p)
+		}
+	} else if x, y := s.self.getPosition(); x == s.lastX && y == s.lastY {
+		// Lost the player
+		s.tracking = false
+	}
+}
+func (s *lineOfSight) eval(p *player, x, y int) float64 {
+	if s.spotted {
+		return s.chase.eval(p, x, y)
+	}
+	if s.tracking {
+		return math.Sqrt(math.Pow(float64(s.lastY-y), 2) + math.Pow(float64(s.lastX-x), 2))
+	}
+	// Wander
+	return float64(random(0, 10))
+}
+func (s *lineOfSight) alerted() bool {
+	return s.spotted
+}
+
+// Vim motions the enemy uses ('g' is gg).
+var vimMotions = []rune{'w', 'b', 'e', 'g', 'G'}
+
+func (s *vimMotion) bind(e *enemy, st *stage) strategy {
+	chase := s.chase
+	if chase == nil {
+		chase = &assault{}
+	}
+	if bs, ok := chase.(boundStrategy); ok {
+		chase = bs.bind(e, st)
+	}
+	interval := s.interval
+	if interval == 0 {
+		interval = vimMotionInterval
+	}
+	return &vimMotion{chase: chase, interval: interval, self: e, stage: st}
+}
+func (s *vimMotion) jump(p *player) (int, int, bool) {
+	e := s.self
+	if s.motion != 0 {
+		// Jump with the announced motion
+		x, y := motionDestination(s.motion, e.x, e.y, *s.stage)
+		s.motion = 0
+		if (x != e.x || y != e.y) && e.canMove(x, y) {
+			return x, y, true
+		}
+		e.draw()
+		return 0, 0, false
+	}
+	s.moves++
+	if s.moves%s.interval != 0 {
+		return 0, 0, false
+	}
+	// Choose the motion that takes the enemy closest to the player
+	best, min := rune(0), s.chase.eval(p, e.x, e.y)
+	for _, m := range vimMotions {
+		x, y := motionDestination(m, e.x, e.y, *s.stage)
+		if (x == e.x && y == e.y) || !e.canMove(x, y) {
+			continue
+		}
+		if v := s.chase.eval(p, x, y); v < min {
+			best, min = m, v
+		}
+	}
+	if best == 0 {
+		return 0, 0, false
+	}
+	// Stay for a move to announce the jump
+	s.motion = best
+	e.draw()
+	return e.x, e.y, true
+}
+func (s *vimMotion) eval(p *player, x, y int) float64 {
+	return s.chase.eval(p, x, y)
+}
+func (s *vimMotion) alerted() bool {
+	return s.motion != 0
+}
+
+func (s *shy) bind(e *enemy, st *stage) strategy {
+	// The home corner is the corner of the stage on the side of the home
+	corner := point{0, 0}
+	if e.homeX >= st.width/2 {
+		corner.x = st.width - 1
+	}
+	if e.homeY >= st.height/2 {
+		corner.y = st.height - 1
+	}
+	return &shy{self: e, corner: corner}
+}
+func (s *shy) eval(p *player, x, y int) float64 {
+	if s.self != nil {
+		ex, ey := s.self.getPosition()
+		if math.Sqrt(math.Pow(float64(p.y-ey), 2)+math.Pow(float64(p.x-ex), 2)) < shyDistance {
+			return math.Sqrt(math.Pow(float64(s.corner.y-y), 2) + math.Pow(float64(s.corner.x-x), 2))
+		}
+	}
+	return math.Sqrt(math.Pow(float64(p.y-y), 2) + math.Pow(float64(p.x-x), 2))
+}
+
+func (s *patrol) bind(e *enemy, st *stage) strategy {
+	pt := &patrol{self: e, route: st.route}
+	// Start from the nearest waypoint
+	nearest := math.MaxInt
+	distance := walkableDistance(point{e.x, e.y}, e.canMove)
+	for i, w := range pt.route {
+		if d, ok := distance[w]; ok && d < nearest {
+			nearest = d
+			pt.next = i
+		}
+	}
+	return pt
+}
+func (s *patrol) plan(p *player) {
+	if len(s.route) == 0 {
+		return
+	}
+	if x, y := s.self.getPosition(); x == s.route[s.next].x && y == s.route[s.next].y {
+		s.next = (s.next + 1) % len(s.route)
+	}
+	s.distance = walkableDistance(s.route[s.next], s.self.canMove)
+}
+func (s *patrol) eval(p *player, x, y int) float64 {
+	if len(s.route) == 0 {
+		// Without waypoints, it stays around its home
+		return math.Sqrt(math.Pow(float64(s.self.homeY-y), 2) + math.Pow(float64(s.self.homeX-x), 2))
+	}
+	if d, ok := s.distance[point{x, y}]; ok {
+		return float64(d)
+	}
+	return 500
+}
+
+// Returns the number of steps from the starting point to each cell that can be reached.
+// Breadth-first search is used because all steps have the same cost.
+func walkableDistance(from point, canMove func(int, int) bool) map[point]int {
+	winWidth, winHeight := screen.size()
+	distance := map[point]int{from: 0}
+	queue := []point{from}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, n := range []point{{c.x, c.y - 1}, {c.x, c.y + 1}, {c.x - 1, c.y}, {c.x + 1, c.y}} {
+			if n.x < 0 || n.y < 0 || n.x >= winWidth || n.y >= winHeight {
+				continue
+			}
+			if _, ok := distance[n]; ok || !canMove(n.x, n.y) {
+				continue
+			}
+			distance[n] = distance[c] + 1
+			queue = append(queue, n)
+		}
+	}
+	return distance
+}
+
 // Return a value between min and max
 // e.g. random(0, 3) returns 0,1,2,3
 func random(min, max int) int {
@@ -131,11 +759,15 @@ func random(min, max int) int {
 
 type iEnemyBuilder interface {
 	displayFormat(rune, string) iEnemyBuilder
-	speed(int) iEnemyBuilder
+	speed(float64) iEnemyBuilder
+	respawn(int) iEnemyBuilder
 	strategize(strategy) iEnemyBuilder
 	movable(func(int, int) bool) iEnemyBuilder
+	sight(func(int, int) bool) iEnemyBuilder
 	defaultHunter() iEnemyBuilder
 	defaultGhost() iEnemyBuilder
+	defaultPatrol() iEnemyBuilder
+	copy() iEnemyBuilder
 	build() iEnemy
 }
 type enemyBuilder struct {
@@ -143,9 +775,10 @@ type enemyBuilder struct {
 	y            int
 	char         rune
 	color        termbox.Attribute
-	waitingTime  int
-	oneActionInN int
+	speedRate    float64
+	respawnDelay int
 	canMove      func(int, int) bool
+	isOpaque     func(int, int) bool
 	strategy     strategy
 }
 
@@ -156,18 +789,29 @@ func (eb *enemyBuilder) displayFormat(r rune, s string) iEnemyBuilder {
 		eb.color = termbox.ColorRed
 	case "CYAN":
 		eb.color = termbox.ColorCyan
+	case "MAGENTA":
+		eb.color = termbox.ColorMagenta
 	}
 	return eb
 }
-func (eb *enemyBuilder) speed(i int) iEnemyBuilder {
-	eb.waitingTime = i
-	eb.oneActionInN = i
+
+// Cells per second
+func (eb *enemyBuilder) speed(f float64) iEnemyBuilder {
+	eb.speedRate = f
+	return eb
+}
+func (eb *enemyBuilder) respawn(i int) iEnemyBuilder {
+	eb.respawnDelay = i
 	return eb
 }
 func (eb *enemyBuilder) movable(fn func(int, int) bool) iEnemyBuilder {
 	eb.canMove = fn
 	return eb
 }
+func (eb *enemyBuilder) sight(fn func(int, int) bool) iEnemyBuilder {
+	eb.isOpaque = fn
+	return eb
+}
 func (eb *enemyBuilder) strategize(s strategy) iEnemyBuilder {
 	eb.strategy = s
 	return eb
@@ -177,13 +821,26 @@ func (eb *enemyBuilder) defaultHunter() iEnemyBuilder {
 	fn := func(x, y int) bool {
 		return !isCharWall(x, y) && !isCharEnemy(x, y)
 	}
-	return eb.displayFormat(chHunter, "RED").speed(1).movable(fn).strategize(&assault{})
+	return eb.displayFormat(chHunter, "RED").speed(1).respawn(defaultRespawnDelay).movable(fn).sight(isCharWall).strategize(&assault{})
 }
 func (eb *enemyBuilder) defaultGhost() iEnemyBuilder {
 	fn := func(x, y int) bool {
 		return !isCharBoundary(x, y) && !isCharEnemy(x, y)
 	}
-	return eb.displayFormat(chGhost, "CYAN").speed(2).movable(fn).strategize(&assault{})
+	// Ghosts see through obstacles
+	return eb.displayFormat(chGhost, "CYAN").speed(0.5).respawn(defaultRespawnDelay).movable(fn).sight(isCharBoundary).strategize(&assault{})
+}
+func (eb *enemyBuilder) defaultPatrol() iEnemyBuilder {
+	fn := func(x, y int) bool {
+		return !isCharWall(x, y) && !isCharEnemy(x, y)
+	}
+	return eb.displayFormat(chPatrol, "MAGENTA").speed(1).respawn(defaultRespawnDelay).movable(fn).sight(isCharWall).strategize(&patrol{})
+}
+
+// Returns a builder with the same settings, so that the builder of a stage is kept as it is.
+func (eb *enemyBuilder) copy() iEnemyBuilder {
+	c := *eb
+	return &c
 }
 
 func newEnemyBuilder() iEnemyBuilder {
@@ -196,9 +853,10 @@ func (eb *enemyBuilder) build() iEnemy {
 		y:            eb.y,
 		char:         eb.char,
 		color:        eb.color,
-		waitingTime:  eb.waitingTime,
-		oneActionInN: eb.oneActionInN,
+		speed:        eb.speedRate,
+		respawnDelay: eb.respawnDelay,
 		canMove:      eb.canMove,
+		isOpaque:     eb.isOpaque,
 		strategy:     eb.strategy,
 		underRune:    underRune{bgColor: termbox.ColorBlack},
 	}
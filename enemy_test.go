@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	termbox "github.com/nsf/termbox-go"
 )
@@ -18,6 +19,9 @@ func TestEnemyControl(t *testing.T) {
 		"hunter with obstacle": {newEnemyBuilder().defaultHunter(), 5, "hunter_with_obstacle.txt"},
 		"ghost with obstacle":  {newEnemyBuilder().defaultGhost(), 6, "ghost_with_obstacle.txt"},
 		"tricky":               {newEnemyBuilder().defaultHunter().strategize(&tricky{}), 5, "hunter_with_obstacle.txt"},
+		"pathfinding":          {newEnemyBuilder().defaultHunter().strategize(&pathfinding{}), 5, "hunter_with_obstacle.txt"},
+		// Check the hunter goes around the obstacle instead of getting stuck.
+		"pathfinding in trap": {newEnemyBuilder().defaultHunter().strategize(&pathfinding{}), 13, "hunter_in_trap.txt"},
 	}
 	for name, tt := range cases {
 		tt := tt
@@ -29,7 +33,7 @@ func TestEnemyControl(t *testing.T) {
 			p.state = continuing
 			count := 0
 			for p.state == continuing {
-				if err := s.control(p); err != nil {
+				if err := s.control(players{p}); err != nil {
 					t.Error(err)
 				}
 				count++
@@ -62,6 +66,7 @@ func TestEnemiesOverlap(t *testing.T) {
 			p.state = continuing
 			count := 0
 			for p.state == continuing {
+				e.accelerate(time.Second, 1)
 				e.move(e.think(p))
 				e.hasCaptured(p)
 				count++
@@ -101,6 +106,358 @@ func TestEnemyThink(t *testing.T) {
 	}
 }
 
+func TestStrategyThink(t *testing.T) {
+	// The hunter is at (5, 3), which is the center of the stage.
+	const hunterX, hunterY = 5, 3
+	cases := map[string]struct {
+		strategy  func(e *enemy) strategy
+		playerX   int
+		playerY   int
+		dirX      int
+		dirY      int
+		expectedX int
+		expectedY int
+	}{
+		// The player below is moving up, so the ambusher goes up ahead of the player.
+		"ambusher ahead of the player": {
+			func(e *enemy) strategy { return &ambusher{} }, 5, 5, 0, -1, 5, 2,
+		},
+		"ambusher without movement": {
+			func(e *enemy) strategy { return &ambusher{} }, 5, 5, 0, 0, 5, 4,
+		},
+		// The partner is below the player, so the flanker goes around from above.
+		"flanker with partner": {
+			func(e *enemy) strategy {
+				return &flanker{partner: &enemy{x: 5, y: 9}}
+			}, 5, 5, 0, 0, 5, 2,
+		},
+		"flanker without partner": {
+			func(e *enemy) strategy { return &flanker{} }, 5, 5, 0, 0, 5, 4,
+		},
+		// The player is close, so the shy enemy retreats to its home corner.
+		"shy near the player": {
+			func(e *enemy) strategy { return &shy{self: e, corner: point{5, 0}} }, 5, 5, 0, 0, 5, 2,
+		},
+		"shy far from the player": {
+			func(e *enemy) strategy { return &shy{self: e, corner: point{5, 0}} }, 5, 5 + shyDistance, 0, 0, 5, 4,
+		},
+	}
+	p, stage, err := enemyActionTestInit(t, enemyTestMapPath+"hunter.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	e := stage.enemies[0].(*enemy)
+	if x, y := e.getPosition(); x != hunterX || y != hunterY {
+		t.Fatalf("expected %d %d but %d %d", hunterX, hunterY, x, y)
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			e.strategy = tt.strategy(e)
+			p.x, p.y = tt.playerX, tt.playerY
+			p.dirX, p.dirY = tt.dirX, tt.dirY
+			x, y := e.think(p)
+			if x != tt.expectedX || y != tt.expectedY {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, x, y)
+			}
+		})
+	}
+}
+
+func TestFlankerBind(t *testing.T) {
+	_, stage, err := enemyActionTestInit(t, enemyTestMapPath+"hunter_with_enemies.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	hunter := stage.enemies[0]
+	ghost := newEnemyBuilder().defaultGhost().build().(*enemy)
+	stage.enemies = append([]iEnemy{ghost}, stage.enemies...)
+	f := (&flanker{partnerChar: chHunter}).bind(ghost, &stage).(*flanker)
+	if f.partner != hunter {
+		t.Errorf("expected %v but %v", hunter, f.partner)
+	}
+	f = (&flanker{partnerChar: chGhost}).bind(ghost, &stage).(*flanker)
+	if f.partner != nil {
+		t.Errorf("expected no partner but %v", f.partner)
+	}
+}
+
+func TestShyBind(t *testing.T) {
+	cases := map[string]struct {
+		homeX     int
+		homeY     int
+		expectedX int
+		expectedY int
+	}{
+		"top left":     {1, 1, 0, 0},
+		"top right":    {18, 2, 19, 0},
+		"bottom left":  {3, 8, 0, 9},
+		"bottom right": {10, 5, 19, 9},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			e := &enemy{}
+			e.setHome(tt.homeX, tt.homeY)
+			s := (&shy{}).bind(e, &stage{width: 20, height: 10}).(*shy)
+			if s.corner.x != tt.expectedX || s.corner.y != tt.expectedY {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, s.corner.x, s.corner.y)
+			}
+		})
+	}
+}
+
+func TestFrightened(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0]
+	homeX, homeY := e.getPosition()
+	p.state = continuing
+	p.energized = true
+	// The frightened enemy moves at half speed, so it takes 2 ticks to move a cell
+	for i := 0; i < 2; i++ {
+		if err := s.control(players{p}); err != nil {
+			t.Error(err)
+		}
+	}
+	// The frightened enemy runs away from the player
+	if x, y := e.getPosition(); x != homeX || y != homeY-1 {
+		t.Errorf("expected %d %d but %d %d", homeX, homeY-1, x, y)
+	}
+	if !isCharFrightened(e.getPosition()) {
+		t.Error("expected the enemy to be displayed as frightened")
+	}
+	// The player captures the frightened enemy, and the enemy leaves the board
+	x, y := e.getPosition()
+	p.x, p.y = x, y
+	e.hasCaptured(p)
+	if p.state != continuing || p.bonus != captureBonus {
+		t.Errorf("expected %d %d but %d %d", continuing, captureBonus, p.state, p.bonus)
+	}
+	if isCharEnemy(x, y) {
+		t.Error("expected the enemy to leave the board")
+	}
+	// The enemy goes back home and respawns after the delay
+	p.x, p.y = 1, 1
+	ticks := 0
+	for !isCharEnemy(homeX, homeY) && ticks < 100 {
+		if err := s.control(players{p}); err != nil {
+			t.Error(err)
+		}
+		ticks++
+	}
+	// eaten -> returning (1 tick), one step home (1 tick), returning -> waiting (1 tick), then the delay
+	if expected := 3 + defaultRespawnDelay; ticks != expected {
+		t.Errorf("expected %d but %d", expected, ticks)
+	}
+	// The enemy is not frightened after it respawns
+	p.x, p.y = e.getPosition()
+	e.hasCaptured(p)
+	if p.state != lose {
+		t.Errorf("expected %d but %d", lose, p.state)
+	}
+}
+
+func TestFrightenedTimeUp(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	s.frightenedTime = 3
+	p.state = continuing
+	p.energized = true
+	for i := 0; i < s.frightenedTime-1; i++ {
+		if err := s.control(players{p}); err != nil {
+			t.Error(err)
+		}
+	}
+	if !isCharFrightened(s.enemies[0].getPosition()) {
+		t.Error("expected the enemy to be frightened")
+	}
+	if err := s.control(players{p}); err != nil {
+		t.Error(err)
+	}
+	if isCharFrightened(s.enemies[0].getPosition()) {
+		t.Error("expected the enemy not to be frightened")
+	}
+}
+
+// Test the frightened time runs out while the enemy is frozen, and the frozen enemy doesn't use its progress.
+func TestFrightenedWhileFrozen(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	s.frightenedTime = 3
+	p.state = continuing
+	p.energized = true
+	p.frozen = freezeTime
+	e := s.enemies[0]
+	x, y := e.getPosition()
+	e.(*enemy).progress = stepCost
+	for i := 0; i < s.frightenedTime; i++ {
+		if err := s.control(players{p}); err != nil {
+			t.Error(err)
+		}
+	}
+	if isCharFrightened(e.getPosition()) {
+		t.Error("expected the enemy not to be frightened")
+	}
+	if nx, ny := e.getPosition(); nx != x || ny != y {
+		t.Errorf("expected %d %d but %d %d", x, y, nx, ny)
+	}
+}
+
+func TestCurrentMode(t *testing.T) {
+	phases := []phase{
+		{mode: scatter, ticks: 2},
+		{mode: chase, ticks: 3},
+		{mode: scatter, ticks: 1},
+		{mode: chase},
+	}
+	cases := map[string]struct {
+		phases   []phase
+		tick     int
+		expected int
+	}{
+		"no phases":        {nil, 0, chase},
+		"first phase":      {phases, 1, scatter},
+		"second phase":     {phases, 2, chase},
+		"third phase":      {phases, 5, scatter},
+		"last phase":       {phases, 6, chase},
+		"last phase lasts": {phases, 100, chase},
+		"only one phase":   {[]phase{{mode: scatter}}, 100, scatter},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := stage{phases: tt.phases, tick: tt.tick}
+			if result := s.currentMode(); result != tt.expected {
+				t.Errorf("expected %d but %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPatrol(t *testing.T) {
+	type position struct {
+		tick int
+		x    int
+		y    int
+	}
+	cases := map[string]struct {
+		waypoints []point
+		digits    bool
+		expected  []position
+	}{
+		// Starts from the nearest waypoint 2, then 3, 4, 1 and 2 again.
+		"digits in the map": {
+			nil,
+			true,
+			[]position{{2, 7, 1}, {4, 7, 3}, {10, 1, 3}, {12, 1, 1}, {18, 7, 1}},
+		},
+		// The waypoints of the manifest are used instead of the digits.
+		// Starts from the nearest waypoint (5, 2), then (3, 2) and (5, 2) again.
+		"manifest": {
+			[]point{{3, 2}, {5, 2}},
+			false,
+			[]position{{1, 5, 2}, {3, 3, 2}, {5, 5, 2}},
+		},
+		// The digits are text of the map unless the stage declares them as waypoints.
+		// Without waypoints, the patrol stays around its home.
+		"digits not declared": {
+			nil,
+			false,
+			[]position{{2, 5, 1}, {4, 5, 1}},
+		},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p, s, err := enemyActionTestInitWithWaypoints(t, enemyTestMapPath+"patrol.txt", newEnemyBuilder().defaultPatrol(), tt.waypoints, tt.digits)
+			if err != nil {
+				t.Error(err)
+			}
+			offset := getOffset(s.height)
+			if d := getCell(1+offset, 1).Ch; tt.digits == (d == '1') {
+				t.Errorf("expected the digit to be kept %v but %q", !tt.digits, d)
+			}
+			e := s.enemies[0]
+			p.state = continuing
+			tick := 0
+			for _, expected := range tt.expected {
+				for tick < expected.tick {
+					if err := s.control(players{p}); err != nil {
+						t.Error(err)
+					}
+					tick++
+				}
+				if x, y := e.getPosition(); x != expected.x+offset || y != expected.y {
+					t.Errorf("expected %d %d at %d but %d %d", expected.x+offset, expected.y, tick, x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestCanSee(t *testing.T) {
+	// The enemy is at (5, 3) and the obstacle is at (4, 4) - (6, 4).
+	cases := map[string]struct {
+		enemyBuilder iEnemyBuilder
+		x            int
+		y            int
+		expected     bool
+	}{
+		"hunter along the row":           {newEnemyBuilder().defaultHunter(), 3, 3, true},
+		"hunter along the column":        {newEnemyBuilder().defaultHunter(), 5, 1, true},
+		"hunter behind the obstacle":     {newEnemyBuilder().defaultHunter(), 5, 5, false},
+		"hunter not in line":             {newEnemyBuilder().defaultHunter(), 4, 5, false},
+		"ghost through the obstacle":     {newEnemyBuilder().defaultGhost(), 5, 5, true},
+		"hunter next to the enemy":       {newEnemyBuilder().defaultHunter(), 6, 3, true},
+		"hunter on the enemy's position": {newEnemyBuilder().defaultHunter(), 5, 3, true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter_with_obstacle.txt", tt.enemyBuilder)
+			if err != nil {
+				t.Error(err)
+			}
+			e := s.enemies[0].(*enemy)
+			if result := e.canSee(tt.x, tt.y); result != tt.expected {
+				t.Errorf("expected %t but %t", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter.txt", newEnemyBuilder().defaultHunter().strategize(&lineOfSight{}))
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0].(*enemy)
+	// The player is in sight, so the enemy chases the player and shows that it has spotted the player.
+	p.x, p.y = 5, 5
+	if x, y := e.think(p); x != 5 || y != 4 {
+		t.Errorf("expected %d %d but %d %d", 5, 4, x, y)
+	}
+	if cell := getCell(e.getPosition()); !e.isAlerted() || cell.Fg == cell.Bg {
+		t.Error("expected the enemy to show that it has spotted the player")
+	}
+	// The player is out of sight, so the enemy goes to where the player was last seen.
+	p.x, p.y = 7, 5
+	if x, y := e.think(p); x != 5 || y != 4 {
+		t.Errorf("expected %d %d but %d %d", 5, 4, x, y)
+	}
+	if cell := getCell(e.getPosition()); e.isAlerted() || cell.Fg != cell.Bg {
+		t.Error("expected the enemy not to show that it has spotted the player")
+	}
+}
+
 func TestRandom(t *testing.T) {
 	const min, max = 1, 5
 	expected := make(map[int]int, max)
@@ -121,6 +478,11 @@ func TestRandom(t *testing.T) {
 }
 
 func enemyActionTestInit(t *testing.T, mapPath string, enemyBuilder iEnemyBuilder) (*player, stage, error) {
+	t.Helper()
+	return enemyActionTestInitWithWaypoints(t, mapPath, enemyBuilder, nil, false)
+}
+
+func enemyActionTestInitWithWaypoints(t *testing.T, mapPath string, enemyBuilder iEnemyBuilder, waypoints []point, digits bool) (*player, stage, error) {
 	t.Helper()
 	if err := termbox.Init(); err != nil {
 		t.Error(err)
@@ -132,9 +494,12 @@ func enemyActionTestInit(t *testing.T, mapPath string, enemyBuilder iEnemyBuilde
 		termbox.Close()
 	})
 	stage := stage{
-		mapPath:       mapPath,
-		hunterBuilder: enemyBuilder,
-		ghostBuilder:  enemyBuilder,
+		mapPath:        mapPath,
+		hunterBuilder:  enemyBuilder,
+		ghostBuilder:   enemyBuilder,
+		patrolBuilder:  enemyBuilder,
+		waypoints:      waypoints,
+		digitWaypoints: digits,
 	}
 	f, err := static.ReadFile(stage.mapPath)
 	if err != nil {
@@ -146,6 +511,32 @@ func enemyActionTestInit(t *testing.T, mapPath string, enemyBuilder iEnemyBuilde
 		return nil, stage, err
 	}
 	p := new(player)
-	stage.plot(b, p)
+	stage.plot(b, players{p})
 	return p, stage, nil
 }
+
+func TestVimMotion(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"vim_motion.txt", newEnemyBuilder().defaultHunter().strategize(&vimMotion{interval: 2}))
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0].(*enemy)
+	// Steps as usual between motions
+	if x, y := e.think(p); x != 4 || y != 2 {
+		t.Errorf("expected %d %d but %d %d", 4, 2, x, y)
+	}
+	// Announces the motion that takes the enemy closest to the player, and stays for a move
+	if x, y := e.think(p); x != 4 || y != 1 {
+		t.Errorf("expected %d %d but %d %d", 4, 1, x, y)
+	}
+	if cell := getCell(e.getPosition()); !e.isAlerted() || cell.Fg == cell.Bg {
+		t.Error("expected the enemy to show the motion")
+	}
+	// Jumps to the first word on the last line with G
+	if x, y := e.think(p); x != 4 || y != 5 {
+		t.Errorf("expected %d %d but %d %d", 4, 5, x, y)
+	}
+	if e.isAlerted() {
+		t.Error("expected the enemy not to show the motion after the jump")
+	}
+}
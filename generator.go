@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var generatorValidationError = errors.New("Generator Validation Error")
+
+// generator builds a random stage map. The same parameters always build the same map.
+type generator struct {
+	seed   int64
+	width  int
+	height int
+	// Ratios of the cells inside the boundary filled with walls, apples and poison
+	walls  float64
+	apples float64
+	poison float64
+	// Number of the enemies
+	hunters int
+	ghosts  int
+}
+
+func newGenerator(seed int64) generator {
+	return generator{
+		seed:    seed,
+		width:   30,
+		height:  12,
+		walls:   0.15,
+		apples:  0.4,
+		poison:  0.02,
+		hunters: 1,
+	}
+}
+
+// Parameters of the n-th stage of the endless mode. The stages get larger and harder as it goes on.
+func endlessGenerator(seed int64, level int) generator {
+	g := newGenerator(seed + int64(level))
+	g.width = atMost(30+2*level, maxStageMapWidth)
+	g.height = atMost(10+level, maxStageMapHeight)
+	g.walls = math.Min(0.1+0.02*float64(level), 0.3)
+	g.poison = math.Min(0.01*float64(level), 0.06)
+	g.hunters = atMost(1+level/3, 3)
+	g.ghosts = atMost(level/2, 2)
+	return g
+}
+
+// Returns the n-th stage of the endless mode.
+func endlessStage(seed int64, level int) (stage, error) {
+	g := endlessGenerator(seed, level)
+	b, err := g.generate()
+	if err != nil {
+		return stage{}, err
+	}
+	return stage{
+		level:         level,
+		mapPath:       g.name(),
+		mapData:       b,
+		hunterBuilder: newEnemyBuilder().defaultHunter().speed(math.Min(0.8+0.1*float64(level), 2)),
+		ghostBuilder:  newEnemyBuilder().defaultGhost(),
+		gameSpeed:     750 * time.Millisecond,
+	}, nil
+}
+
+// Name of the generated map used in error messages.
+func (g generator) name() string {
+	return "generated/seed-" + strconv.FormatInt(g.seed, 10) + ".txt"
+}
+
+func (g generator) validate() error {
+	var msg string
+	switch {
+	case g.width < 5 || g.width > maxStageMapWidth:
+		msg = "Width must be between 5 and " + strconv.Itoa(maxStageMapWidth)
+	case g.height < 5 || g.height > maxStageMapHeight:
+		msg = "Height must be between 5 and " + strconv.Itoa(maxStageMapHeight)
+	case g.walls < 0 || g.walls > 0.5:
+		msg = "Density of walls must be between 0 and 0.5"
+	case g.apples <= 0 || g.poison < 0 || g.apples+g.poison > 0.9:
+		msg = "Density of apples and poison must be between 0 and 0.9 in total"
+	case g.hunters < 0 || g.ghosts < 0 || g.hunters+g.ghosts > 9:
+		msg = "Number of enemies must be between 0 and 9"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: %+v", generatorValidationError, errors.New(g.name()+"; "+msg+";"))
+}
+
+// Builds the map. Every apple can be reached from the player without touching poison.
+func (g generator) generate() ([]byte, error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(g.seed))
+	cells := make([][]rune, g.height)
+	for y := range cells {
+		cells[y] = make([]rune, g.width)
+		for x := range cells[y] {
+			if y == 0 || y == g.height-1 || x == 0 || x == g.width-1 {
+				cells[y][x] = chBoundary
+			} else {
+				cells[y][x] = chSpace
+			}
+		}
+	}
+	inner := (g.width - 2) * (g.height - 2)
+	start := point{1 + rnd.Intn(g.width-2), 1 + rnd.Intn(g.height-2)}
+
+	// Walls are short segments, so that they look like the walls of the other stages.
+	// Walls and poison never cut off cells from the player.
+	walls := int(math.Round(g.walls * float64(inner)))
+	for placed, tries := 0, 0; placed < walls && tries < inner*10; tries++ {
+		dx, dy, r := 1, 0, chObstacle1
+		if rnd.Intn(2) == 0 {
+			dx, dy, r = 0, 1, chObstacle2
+		}
+		x, y := 1+rnd.Intn(g.width-2), 1+rnd.Intn(g.height-2)
+		length := 2 + rnd.Intn(3)
+		segment := []point{}
+		for i := 0; i < length && cells[y][x] == chSpace && (point{x, y}) != start; i++ {
+			segment = append(segment, point{x, y})
+			x, y = x+dx, y+dy
+		}
+		// A wall of a cell can't be drawn as '-' or '|'
+		if len(segment) < 2 {
+			continue
+		}
+		if placeIfConnected(cells, start, segment, r) {
+			placed += len(segment)
+		}
+	}
+	poison := int(math.Round(g.poison * float64(inner)))
+	for _, c := range shuffledCells(rnd, cells, start) {
+		if poison == 0 {
+			break
+		}
+		if cells[c.y][c.x] == chSpace && placeIfConnected(cells, start, []point{c}, chPoison) {
+			poison--
+		}
+	}
+
+	distances := reachableCells(cells, start)
+	open := []point{}
+	for _, c := range shuffledCells(rnd, cells, start) {
+		if cells[c.y][c.x] == chSpace {
+			open = append(open, c)
+		}
+	}
+	enemies := g.hunters + g.ghosts
+	apples := int(math.Round(g.apples * float64(len(open))))
+	if apples == 0 {
+		apples = 1
+	}
+	if apples+enemies > len(open) {
+		err := errors.New(g.name() + "; The stage is too crowded, decrease the density of walls, poison or apples;")
+		return nil, fmt.Errorf("%w: %+v", generatorValidationError, err)
+	}
+	// Enemies start far from the player
+	spaces := open[apples:]
+	for i := 0; i < enemies; i++ {
+		far := i
+		for j := i + 1; j < len(spaces); j++ {
+			if distances[spaces[j]] > distances[spaces[far]] {
+				far = j
+			}
+		}
+		spaces[i], spaces[far] = spaces[far], spaces[i]
+		r := chHunter
+		if i >= g.hunters {
+			r = chGhost
+		}
+		cells[spaces[i].y][spaces[i].x] = r
+	}
+	for _, c := range open[:apples] {
+		cells[c.y][c.x] = chApple
+	}
+	cells[start.y][start.x] = chPlayer
+
+	lines := make([]string, g.height)
+	for y := range cells {
+		lines[y] = string(cells[y])
+	}
+	b := []byte(strings.Join(lines, "\n"))
+	if err := validateStage(b, g.name()); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func atMost(v, limit int) int {
+	if v > limit {
+		return limit
+	}
+	return v
+}
+
+// Puts the character on the cells unless it cuts off other cells from the start.
+func placeIfConnected(cells [][]rune, start point, points []point, r rune) bool {
+	open := 0
+	for y := range cells {
+		for x := range cells[y] {
+			if cells[y][x] == chSpace {
+				open++
+			}
+		}
+	}
+	for _, c := range points {
+		cells[c.y][c.x] = r
+	}
+	if len(reachableCells(cells, start)) == open-len(points) {
+		return true
+	}
+	for _, c := range points {
+		cells[c.y][c.x] = chSpace
+	}
+	return false
+}
+
+// Returns the cells inside the boundary except the start in random order.
+func shuffledCells(rnd *rand.Rand, cells [][]rune, start point) []point {
+	points := []point{}
+	for y := 1; y < len(cells)-1; y++ {
+		for x := 1; x < len(cells[y])-1; x++ {
+			if (point{x, y}) != start {
+				points = append(points, point{x, y})
+			}
+		}
+	}
+	rnd.Shuffle(len(points), func(i, j int) {
+		points[i], points[j] = points[j], points[i]
+	})
+	return points
+}
+
+// Returns the distances of the empty cells reachable from the start.
+func reachableCells(cells [][]rune, start point) map[point]int {
+	distances := map[point]int{start: 0}
+	queue := []point{start}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, d := range []point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			n := point{c.x + d.x, c.y + d.y}
+			if _, ok := distances[n]; ok {
+				continue
+			}
+			if cells[n.y][n.x] != chSpace {
+				continue
+			}
+			distances[n] = distances[c] + 1
+			queue = append(queue, n)
+		}
+	}
+	return distances
+}
+
+// pacvim generate [options] prints a generated stage map.
+func runGenerate(args []string, w io.Writer, errW io.Writer) error {
+	g := newGenerator(0)
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	fs.Int64Var(&g.seed, "seed", 0, "Seed of the stage. (default random)")
+	fs.IntVar(&g.width, "width", g.width, "Width of the stage including the boundary.")
+	fs.IntVar(&g.height, "height", g.height, "Height of the stage including the boundary.")
+	fs.Float64Var(&g.walls, "walls", g.walls, "Density of walls.")
+	fs.Float64Var(&g.apples, "apples", g.apples, "Density of apples.")
+	fs.Float64Var(&g.poison, "poison", g.poison, "Density of poison.")
+	fs.IntVar(&g.hunters, "hunters", g.hunters, "Number of hunters.")
+	fs.IntVar(&g.ghosts, "ghosts", g.ghosts, "Number of ghosts.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if g.seed == 0 {
+		g.seed = time.Now().UnixNano()
+		// Print the seed to build the same stage again
+		fmt.Fprintln(errW, "seed:", g.seed)
+	}
+	b, err := g.generate()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	cases := map[string]struct {
+		generator generator
+	}{
+		"default":         {newGenerator(1)},
+		"smallest":        {generator{seed: 2, width: 5, height: 5, apples: 0.5}},
+		"largest":         {generator{seed: 3, width: 50, height: 20, walls: 0.3, apples: 0.4, poison: 0.05, hunters: 3, ghosts: 2}},
+		"dense walls":     {generator{seed: 4, width: 20, height: 10, walls: 0.5, apples: 0.3, poison: 0.1, hunters: 1}},
+		"no walls":        {generator{seed: 5, width: 20, height: 10, apples: 0.9, hunters: 2}},
+		"endless level 1": {endlessGenerator(6, 1)},
+		"endless level 9": {endlessGenerator(7, 9)},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			b, err := tt.generator.generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := validateStage(b, name); err != nil {
+				t.Error(err)
+			}
+			lines := strings.Split(string(b), "\n")
+			if len(lines) != tt.generator.height || len(lines[0]) != tt.generator.width {
+				t.Errorf("expected %dx%d but %dx%d", tt.generator.width, tt.generator.height, len(lines[0]), len(lines))
+			}
+			if n := strings.Count(string(b), string(chPlayer)); n != 1 {
+				t.Errorf("expected %d but %d", 1, n)
+			}
+			if n := strings.Count(string(b), string(chHunter)); n != tt.generator.hunters {
+				t.Errorf("expected %d but %d", tt.generator.hunters, n)
+			}
+			if n := strings.Count(string(b), string(chGhost)); n != tt.generator.ghosts {
+				t.Errorf("expected %d but %d", tt.generator.ghosts, n)
+			}
+			if strings.Count(string(b), string(chApple)) == 0 {
+				t.Error("expected apples")
+			}
+			// Every apple can be reached from the player without touching walls or poison
+			reached := map[point]bool{}
+			var visit func(x, y int)
+			visit = func(x, y int) {
+				if reached[point{x, y}] || strings.ContainsRune("+-|!X", rune(lines[y][x])) {
+					return
+				}
+				reached[point{x, y}] = true
+				visit(x+1, y)
+				visit(x-1, y)
+				visit(x, y+1)
+				visit(x, y-1)
+			}
+			for y, l := range lines {
+				if x := strings.IndexRune(l, chPlayer); x >= 0 {
+					visit(x, y)
+				}
+			}
+			for y, l := range lines {
+				for x, r := range l {
+					if r == chApple && !reached[point{x, y}] {
+						t.Errorf("expected the apple at %d %d to be reachable", x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Test the same seed builds the same stage.
+func TestGenerateReproducible(t *testing.T) {
+	g := newGenerator(42)
+	a, err := g.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := g.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("expected\n%s\nbut\n%s", a, b)
+	}
+	c, err := newGenerator(43).generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, c) {
+		t.Error("expected another stage from another seed")
+	}
+}
+
+func TestGenerateValidation(t *testing.T) {
+	cases := map[string]struct {
+		generator generator
+	}{
+		"too narrow":     {generator{width: 4, height: 10, apples: 0.4}},
+		"too wide":       {generator{width: 51, height: 10, apples: 0.4}},
+		"too high":       {generator{width: 30, height: 21, apples: 0.4}},
+		"too many walls": {generator{width: 30, height: 10, walls: 0.6, apples: 0.4}},
+		"no apples":      {generator{width: 30, height: 10}},
+		"too much food":  {generator{width: 30, height: 10, apples: 0.8, poison: 0.2}},
+		"too many enemy": {generator{width: 30, height: 10, apples: 0.4, hunters: 5, ghosts: 5}},
+		"too crowded":    {generator{width: 5, height: 5, apples: 0.9, hunters: 9}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if _, err := tt.generator.generate(); !errors.Is(err, generatorValidationError) {
+				t.Errorf("expected %v but %v", generatorValidationError, err)
+			}
+		})
+	}
+}
+
+// Test the stages of the endless mode are playable and read from memory.
+func TestEndlessStage(t *testing.T) {
+	for level := 1; level <= 20; level++ {
+		s, err := endlessStage(100, level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := s.readMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, s.mapData) {
+			t.Errorf("expected the map of level %d to be read from memory", level)
+		}
+		if s.level != level {
+			t.Errorf("expected %d but %d", level, s.level)
+		}
+	}
+}
+
+func TestRunGenerate(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if err := runGenerate([]string{"-seed", "42"}, &out, &errOut); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := newGenerator(42).generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSuffix(out.String(), "\n") != string(expected) {
+		t.Errorf("expected\n%s\nbut\n%s", expected, out.String())
+	}
+	if err := runGenerate([]string{"-width", "100"}, &out, &errOut); !errors.Is(err, generatorValidationError) {
+		t.Errorf("expected %v but %v", generatorValidationError, err)
+	}
+}
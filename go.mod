@@ -3,14 +3,19 @@ module github.com/masahiro-kasatani/pacvim
 go 1.20
 
 require (
+	github.com/gliderlabs/ssh v0.3.5
 	github.com/nsf/termbox-go v1.1.1
 	github.com/stretchr/testify v1.8.2
+	go.starlark.net v0.0.0-20230525235612-a134d8f9ddca
+	golang.org/x/crypto v0.0.0-20220826181053-bd7e27e6170d
 	golang.org/x/sync v0.1.0
 )
 
 require (
+	github.com/anmitsu/go-shlex v0.0.0-20200514113438-38f4b401e2be // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20220825204002-c680a09ffe64 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
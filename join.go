@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Join the game served by "pacvim serve" and play it on the terminal.
+func runJoin(args []string, errW io.Writer) error {
+	fs := flag.NewFlagSet("join", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("Usage: pacvim join host:port")
+	}
+	addr := fs.Arg(0)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+	defer termbox.Close()
+	if err := termbox.Clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
+		return err
+	}
+
+	done := make(chan error, 2)
+	go func() {
+		done <- receiveScreen(conn, terminal{}, addr)
+	}()
+	go func() {
+		done <- sendKeys(conn)
+	}()
+	return <-done
+}
+
+// Send the keys typed on the terminal to the server until q is typed.
+func sendKeys(w io.Writer) error {
+	for {
+		ev := termbox.PollEvent()
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+		if ev.Ch == 'q' {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "key %d %d\n", ev.Key, ev.Ch); err != nil {
+			return err
+		}
+	}
+}
+
+// Draw the screen sent by the server until it says bye.
+func receiveScreen(r io.Reader, dst iScreen, addr string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, nums := parseMessage(scanner.Text())
+		switch {
+		case name == "cell" && len(nums) == 5:
+			dst.setCell(nums[0], nums[1], rune(nums[2]), termbox.Attribute(nums[3]), termbox.Attribute(nums[4]))
+		case name == "cursor" && len(nums) == 2:
+			dst.setCursor(nums[0], nums[1])
+		case name == "hide" && len(nums) == 0:
+			dst.hideCursor()
+		case name == "flush" && len(nums) == 0:
+			if err := dst.flush(); err != nil {
+				return err
+			}
+		case name == "bye" && len(nums) == 0:
+			return nil
+		default:
+			err := errors.New(addr + "; Invalid message: " + scanner.Text() + ";")
+			return fmt.Errorf("%w: %+v", protocolValidationError, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Returns the name and the numbers of the message, e.g. "cursor 3 4".
+// The name is empty if the message is not a name followed by numbers.
+func parseMessage(text string) (string, []int) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	nums := []int{}
+	for _, f := range fields[1:] {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return "", nil
+		}
+		nums = append(nums, n)
+	}
+	return fields[0], nums
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	chEsc = '\x1b'
+
+	// Limit of nested recursive mappings, like 'maxmapdepth' in Vim.
+	maxMapDepth = 100
+)
+
+var keymapValidationError = errors.New("Key Mapping Validation Error")
+
+// keymap holds the mappings read from pacvimrc.
+// The key of mappings is the left-hand side of the mapping.
+type keymap struct {
+	mappings map[string]mapping
+}
+
+type mapping struct {
+	lhs       string
+	rhs       []rune
+	recursive bool
+	// Line of pacvimrc the mapping is on, for errors
+	line int
+}
+
+// Special keys that can be written in pacvimrc.
+var keyNotations = map[string][]rune{
+	"<space>":  {' '},
+	"<lt>":     {'<'},
+	"<bar>":    {'|'},
+	"<bslash>": {'\\'},
+	"<esc>":    {chEsc},
+	"<nop>":    {},
+}
+
+// Default location of pacvimrc.
+func defaultKeymapPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "pacvim", "pacvimrc")
+}
+
+// Read pacvimrc.
+// If the file is not given explicitly, a missing file is not an error.
+func loadKeymap(filePath string, required bool) (keymap, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return keymap{}, nil
+		}
+		return keymap{}, err
+	}
+	defer f.Close()
+	return parseKeymap(f, filePath)
+}
+
+func parseKeymap(r io.Reader, filePath string) (keymap, error) {
+	km := keymap{mappings: map[string]mapping{}}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		// Skip blank lines and comments
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "\"") {
+			continue
+		}
+		var recursive bool
+		switch fields[0] {
+		case "map", "nmap", "nm":
+			recursive = true
+		case "noremap", "nnoremap", "no", "nn", "nno":
+			recursive = false
+		default:
+			return keymap{}, keymapError(filePath, lineNo, "Unknown command: "+fields[0])
+		}
+		if len(fields) != 3 {
+			return keymap{}, keymapError(filePath, lineNo, "Write a mapping as '"+fields[0]+" {lhs} {rhs}'")
+		}
+		lhs, err := parseKeys(fields[1])
+		if err != nil {
+			return keymap{}, keymapError(filePath, lineNo, err.Error())
+		}
+		if len(lhs) == 0 {
+			return keymap{}, keymapError(filePath, lineNo, "The left-hand side of a mapping must not be empty")
+		}
+		rhs, err := parseKeys(fields[2])
+		if err != nil {
+			return keymap{}, keymapError(filePath, lineNo, err.Error())
+		}
+		km.mappings[string(lhs)] = mapping{lhs: string(lhs), rhs: rhs, recursive: recursive, line: lineNo}
+	}
+	if err := scanner.Err(); err != nil {
+		return keymap{}, err
+	}
+	// The first error in the file is reported
+	mappings := []mapping{}
+	for _, m := range km.mappings {
+		mappings = append(mappings, m)
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].line < mappings[j].line
+	})
+	for _, m := range mappings {
+		if _, ok := km.expand(m, 0); !ok {
+			return keymap{}, keymapError(filePath, m.line, "Recursive mapping: "+m.lhs)
+		}
+	}
+	return km, nil
+}
+
+func keymapError(filePath string, lineNo int, msg string) error {
+	err := errors.New(filePath + "; " + msg + " (line " + strconv.Itoa(lineNo) + ");")
+	return fmt.Errorf("%w: %+v", keymapValidationError, err)
+}
+
+// Convert the key notation (e.g. "<Space>w") to runes.
+func parseKeys(s string) ([]rune, error) {
+	keys := []rune{}
+	for len(s) > 0 {
+		if s[0] == '<' {
+			if end := strings.IndexByte(s, '>'); end > 0 {
+				notation := strings.ToLower(s[:end+1])
+				if r, ok := keyNotations[notation]; ok {
+					keys = append(keys, r...)
+					s = s[end+1:]
+					continue
+				}
+				if strings.IndexByte(s[1:end], '<') < 0 {
+					return nil, errors.New("Unknown key notation: " + s[:end+1])
+				}
+			}
+		}
+		r := []rune(s)[0]
+		keys = append(keys, r)
+		s = s[len(string(r)):]
+	}
+	return keys, nil
+}
+
+// Apply the mappings to the typed keys.
+// Returns the keys to be processed and the keys waiting for the rest of a mapping.
+func (km keymap) apply(keys []rune) ([]rune, []rune) {
+	out := []rune{}
+	for len(keys) > 0 {
+		if km.isPrefix(keys) {
+			return out, keys
+		}
+		n, m, ok := km.longestMatch(keys)
+		if !ok {
+			out = append(out, keys[0])
+			keys = keys[1:]
+			continue
+		}
+		keys = keys[n:]
+		rhs, _ := km.expand(m, 0)
+		out = append(out, rhs...)
+	}
+	return out, nil
+}
+
+// Returns the right-hand side of the mapping.
+// Keys of recursive mappings are mapped again until they no longer match.
+func (km keymap) expand(m mapping, depth int) ([]rune, bool) {
+	if !m.recursive {
+		return m.rhs, true
+	}
+	if depth >= maxMapDepth {
+		return nil, false
+	}
+	out := []rune{}
+	keys := m.rhs
+	// Like Vim, the right-hand side beginning with its own left-hand side doesn't map it again, e.g. nmap w wl
+	if strings.HasPrefix(string(keys), m.lhs) {
+		out = append(out, []rune(m.lhs)...)
+		keys = keys[len([]rune(m.lhs)):]
+	}
+	for len(keys) > 0 {
+		n, next, ok := km.longestMatch(keys)
+		if !ok {
+			out = append(out, keys[0])
+			keys = keys[1:]
+			continue
+		}
+		keys = keys[n:]
+		rhs, ok := km.expand(next, depth+1)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, rhs...)
+	}
+	return out, true
+}
+
+// Whether keys are the beginning of a longer mapping.
+func (km keymap) isPrefix(keys []rune) bool {
+	for lhs := range km.mappings {
+		if len(lhs) > len(string(keys)) && strings.HasPrefix(lhs, string(keys)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the mapping whose left-hand side is the longest match at the beginning of keys
+// and the number of keys it consumes.
+func (km keymap) longestMatch(keys []rune) (int, mapping, bool) {
+	var lhs string
+	for k := range km.mappings {
+		if len(k) > len(lhs) && strings.HasPrefix(string(keys), k) {
+			lhs = k
+		}
+	}
+	if lhs == "" {
+		return 0, mapping{}, false
+	}
+	return len([]rune(lhs)), km.mappings[lhs], true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeymap(t *testing.T) {
+	cases := map[string]struct {
+		rc       string
+		expected string
+	}{
+		"normal": {
+			"\" comment\n\nnnoremap <Space>w 3w\nmap H 0\n",
+			"",
+		},
+		"error unknown command": {
+			"imap jj <Esc>",
+			"Key Mapping Validation Error: pacvimrc; Unknown command: imap (line 1);",
+		},
+		"error missing rhs": {
+			"\" comment\nnnoremap H",
+			"Key Mapping Validation Error: pacvimrc; Write a mapping as 'nnoremap {lhs} {rhs}' (line 2);",
+		},
+		"error unknown key notation": {
+			"nnoremap <C-a> w",
+			"Key Mapping Validation Error: pacvimrc; Unknown key notation: <C-a> (line 1);",
+		},
+		"error empty lhs": {
+			"nnoremap <Nop> w",
+			"Key Mapping Validation Error: pacvimrc; The left-hand side of a mapping must not be empty (line 1);",
+		},
+		"error recursive mapping": {
+			"map H 0\nmap a b\nmap b a",
+			"Key Mapping Validation Error: pacvimrc; Recursive mapping: a (line 2);",
+		},
+		"rhs beginning with lhs": {
+			"nmap w wl\nmap j jzz\nmap x w",
+			"",
+		},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := parseKeymap(strings.NewReader(tt.rc), "pacvimrc")
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestKeymapApply(t *testing.T) {
+	const rc = `
+nnoremap <Space>w 3w
+nnoremap ; $
+nnoremap s 0
+nnoremap st G
+map H s
+nnoremap j gj
+map n j
+nnoremap x <Nop>
+nmap e el
+map E e
+`
+	km, err := parseKeymap(strings.NewReader(rc), "pacvimrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		keys            string
+		expected        string
+		expectedPending string
+	}{
+		"not mapped":                  {"w", "w", ""},
+		"mapped":                      {";", "$", ""},
+		"sequence":                    {" w", "3w", ""},
+		"wait for the sequence":       {" ", "", " "},
+		"longest match":               {"st", "G", ""},
+		"shorter match":               {"sw", "0w", ""},
+		"wait for the longest match":  {"s", "", "s"},
+		"recursive":                   {"H", "0", ""},
+		"not recursive":               {"j", "gj", ""},
+		"recursive to not recursive":  {"n", "gj", ""},
+		"no operation":                {"xw", "w", ""},
+		"not mapped after a sequence": {" wk", "3wk", ""},
+		"rhs beginning with lhs":      {"e", "el", ""},
+		"to rhs beginning with lhs":   {"E", "el", ""},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			keys, pending := km.apply([]rune(tt.keys))
+			if string(keys) != tt.expected || string(pending) != tt.expectedPending {
+				t.Errorf("expected %q %q but %q %q", tt.expected, tt.expectedPending, string(keys), string(pending))
+			}
+		})
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	termbox "github.com/nsf/termbox-go"
 )
@@ -25,18 +27,21 @@ const (
 	chPlayer    = 'P'
 	chHunter    = 'H'
 	chGhost     = 'G'
+	chPatrol    = 'R'
 	chApple     = 'o'
 	chSpace     = ' '
 	chPoison    = 'X'
+	chPellet    = '@'
 	chBoundary  = '+'
 	chObstacle1 = '-'
 	chObstacle2 = '|'
 	chObstacle3 = '!'
 
-	sceneStart   = "files/scene/start.txt"
-	sceneYouwin  = "files/scene/youwin.txt"
-	sceneYoulose = "files/scene/youlose.txt"
-	sceneGoodbye = "files/scene/goodbye.txt"
+	sceneStart    = "files/scene/start.txt"
+	sceneYouwin   = "files/scene/youwin.txt"
+	sceneYoulose  = "files/scene/youlose.txt"
+	sceneGoodbye  = "files/scene/goodbye.txt"
+	sceneCongrats = "files/scene/congrats.txt"
 )
 
 //go:embed files
@@ -49,16 +54,130 @@ func main() {
 }
 
 func run() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "generate":
+			return runGenerate(os.Args[2:], os.Stdout, os.Stderr)
+		case "serve":
+			return runServe(os.Args[2:], os.Stdout, os.Stderr)
+		case "join":
+			return runJoin(os.Args[2:], os.Stderr)
+		case "ssh":
+			return runSSH(os.Args[2:], os.Stdout, os.Stderr)
+		case "session":
+			return runSession(os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+		}
+	}
+
 	stages := initStages()
 	if err := validateFiles(stages); err != nil {
 		return err
 	}
+	if err := validateFiles(initLessons()); err != nil {
+		return err
+	}
 
 	level := flag.Int("level", stages[0].level, "Level at the start of the game.")
 	life := flag.Int("life", 2, "Remaining lives.")
+	rc := flag.String("rc", "", "Path of the key mapping file. (default ~/.config/pacvim/pacvimrc)")
+	scripts := flag.String("scripts", "", "Directory of the enemy scripts used instead of the embedded ones.")
+	endless := flag.Bool("endless", false, "Play generated stages one after another. -level sets the difficulty at the start.")
+	seed := flag.Int64("seed", 0, "Seed of the generated stages in the endless mode. (default random)")
+	tutorial := flag.Bool("tutorial", false, "Learn the motions one by one in lessons.")
+	daily := flag.Bool("daily", false, "Play the stage of the day and print the result to share.")
+	codePath := flag.String("code", "", "Path of a source file to play as a stage.")
+	codeLine := flag.Int("code-line", 1, "Line of the source file at the top of the stage.")
+	codeWalls := flag.String("code-walls", strings.Join(goKeywords, ","), "Comma-separated tokens of the source file turned into walls.")
+	codePoison := flag.String("code-poison", "nil,panic", "Comma-separated tokens of the source file turned into poison.")
+	modeName := flag.String("mode", "", "Game mode: normal, timeattack or survival. (default chosen in the settings of the menu)")
+	twoPlayerName := flag.String("two-player", "", "Play with two players on one keyboard: coop or versus.")
 	flag.Parse()
 
-	stages = splitStages(stages, level)
+	gameMode := normalGame
+	if *modeName != "" {
+		m, err := parseGameMode(*modeName)
+		if err != nil {
+			return err
+		}
+		gameMode = m
+	}
+	twoPlayer := onePlayer
+	if *twoPlayerName != "" {
+		m, err := parseTwoPlayerMode(*twoPlayerName)
+		if err != nil {
+			return err
+		}
+		twoPlayer = m
+	}
+
+	modes := 0
+	for _, on := range []bool{*codePath != "", *endless, *tutorial, *daily} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return errors.New("Choose one of -code, -endless, -tutorial and -daily")
+	}
+	if twoPlayer != onePlayer && (*tutorial || *daily) {
+		return errors.New("-two-player can't be used with -tutorial and -daily")
+	}
+	// The menu is shown unless the flags choose what to play
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	useMenu := modes == 0 && !given["level"] && !given["mode"]
+	today := time.Now()
+	switch {
+	case *codePath != "":
+		c := newCode(*codePath)
+		c.line = *codeLine
+		c.walls = strings.Split(*codeWalls, ",")
+		c.poison = strings.Split(*codePoison, ",")
+		s, err := c.stage()
+		if err != nil {
+			return err
+		}
+		stages = []stage{s}
+	case *tutorial:
+		stages = initLessons()
+	case *daily:
+		s, err := dailyStage(today)
+		if err != nil {
+			return err
+		}
+		stages = []stage{s}
+	case *endless:
+		stages = nil
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+	default:
+		if !useMenu {
+			stages = splitStages(stages, level)
+		}
+	}
+	for i := range stages {
+		stages[i].scriptDir = *scripts
+		if _, err := stages[i].readScript(); err != nil {
+			return err
+		}
+	}
+
+	rcPath, required := defaultKeymapPath(), false
+	if *rc != "" {
+		rcPath, required = *rc, true
+	}
+	km, err := loadKeymap(rcPath, required)
+	if err != nil {
+		return err
+	}
+	progressPath := defaultProgressPath()
+	pr, err := loadProgress(progressPath)
+	if err != nil {
+		return err
+	}
 
 	if err := termbox.Init(); err != nil {
 		return err
@@ -66,43 +185,140 @@ func run() error {
 	if err := termbox.Clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
 		return err
 	}
-	defer termbox.Close()
+	// The result of the daily challenge is printed after the screen is restored
+	var result *dailyResult
+	if *daily {
+		result = &dailyResult{date: today, level: stages[0].level}
+	}
+	defer func() {
+		termbox.Close()
+		if result != nil {
+			fmt.Print(result.summary())
+		}
+	}()
 
-	if err := switchScene(sceneStart); err != nil {
+	if useMenu {
+		start, ok, err := runMenu(stages, pr)
+		if err != nil {
+			return err
+		}
+		// Keep the settings
+		if err := pr.save(progressPath); err != nil {
+			return err
+		}
+		if !ok {
+			return switchScene(sceneGoodbye)
+		}
+		stages = stages[start:]
+		gameMode = pr.gameMode
+		if !given["life"] {
+			*life = pr.life
+		}
+	} else if err := switchScene(sceneStart); err != nil {
 		return err
 	}
 
+	// Total time of the cleared stages in the time attack, or the time survived in the survival
+	var total time.Duration
+
 	i := 0
 game:
-	for i < len(stages) && *life >= 0 {
-		p := new(player)
-		if err := stages[i].init(p, *life); err != nil {
+	for (*endless || i < len(stages)) && *life >= 0 {
+		if i == len(stages) {
+			s, err := endlessStage(*seed, *level+i)
+			if err != nil {
+				return err
+			}
+			s.scriptDir = *scripts
+			stages = append(stages, s)
+		}
+		stages[i].gameMode = gameMode
+		ps := newPlayers(km, twoPlayer)
+		stages[i].numPlayers = len(ps)
+		if err := stages[i].init(ps, *life); err != nil {
 			return err
 		}
 
-		standBy(p)
+		standBy(ps)
 
-		if err := stages[i].start(p); err != nil {
+		if err := stages[i].start(ps); err != nil {
 			return err
 		}
+		// The first player plays alone except in the two-player mode
+		p := ps[0]
+		for _, q := range ps {
+			*life += q.extraLives
+		}
+		if result != nil {
+			result.add(p)
+		}
 
-		switch p.state {
+		switch ps.state() {
 		case win:
-			if err := switchScene(sceneYouwin); err != nil {
+			// A lesson is played again until the player uses its motions
+			if !stages[i].lesson.passed(p) {
+				continue
+			}
+			messages := []string{}
+			if gameMode == timeAttack {
+				total += p.playTime
+				messages = append(messages, "Time: "+formatTime(p.playTime))
+			}
+			if r := ps.result(twoPlayer); r != "" {
+				messages = append(messages, r)
+			}
+			if err := switchScene(sceneYouwin, messages...); err != nil {
 				return err
 			}
+			// The progress of the stages is saved to continue later
+			if modes == 0 && twoPlayer == onePlayer {
+				next := 0
+				if i+1 < len(stages) {
+					next = stages[i+1].level
+				}
+				pr.record(stages[i].level, p.score+p.bonus, next)
+				if err := pr.save(progressPath); err != nil {
+					return err
+				}
+			}
 			i++
+			if *tutorial && i == len(stages) {
+				if err := switchScene(sceneCongrats); err != nil {
+					return err
+				}
+			}
 		case lose:
-			if err := switchScene(sceneYoulose); err != nil {
+			messages := []string{}
+			if gameMode == survival {
+				total += p.playTime
+				messages = append(messages, "Survived: "+formatTime(p.playTime))
+			}
+			if r := ps.result(twoPlayer); r != "" {
+				messages = append(messages, r)
+			}
+			if err := switchScene(sceneYoulose, messages...); err != nil {
 				return err
 			}
 			*life--
 		case quit:
+			if gameMode == survival {
+				total += p.playTime
+			}
 			break game
 		}
 	}
 
-	if err := switchScene(sceneGoodbye); err != nil {
+	if result != nil {
+		result.lives = *life
+	}
+	messages := []string{}
+	switch {
+	case gameMode == timeAttack && i == len(stages) && !*endless:
+		messages = append(messages, "Total time: "+formatTime(total))
+	case gameMode == survival && total > 0:
+		messages = append(messages, "Total survived: "+formatTime(total))
+	}
+	if err := switchScene(sceneGoodbye, messages...); err != nil {
 		return err
 	}
 
@@ -118,19 +334,20 @@ func splitStages(stages []stage, level *int) []stage {
 	return stages
 }
 
-func standBy(p *player) {
-	p.state = pose
-	for {
-		ev := termbox.PollEvent()
+func standBy(ps players) {
+	state := pose
+	for state == pose {
+		ev := screen.pollEvent()
 		if ev.Key == termbox.KeyEnter {
-			p.state = continuing
-			break
+			state = continuing
 		}
 		if ev.Ch == 'q' {
-			p.state = quit
-			break
+			state = quit
 		}
 	}
+	for _, p := range ps {
+		p.state = state
+	}
 }
 
 var (
@@ -158,6 +375,12 @@ func validateFiles(stages []stage) error {
 		if err := validateStage(b, s.mapPath); err != nil {
 			return err
 		}
+		if _, err := loadSpeedCurve(speedCurvePath(s.mapPath)); err != nil {
+			return err
+		}
+		if _, err := s.readScript(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -182,6 +405,9 @@ func validateStage(b []byte, filePath string) error {
 	if err := validateStageBoundary(filePath, lines); err != nil {
 		return err
 	}
+	if err := validateStageTeleporters(filePath, lines); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -243,3 +469,15 @@ func validateStageBoundary(filePath string, lines []string) error {
 	}
 	return nil
 }
+
+func validateStageTeleporters(filePath string, lines []string) error {
+	count := 0
+	for _, line := range lines {
+		count += strings.Count(line, string(chTeleporter))
+	}
+	if count%2 != 0 {
+		err := errors.New(filePath + "; Place teleporters '" + string(chTeleporter) + "' in pairs;")
+		return fmt.Errorf("%w: %+v", stageMapValidationError, err)
+	}
+	return nil
+}
@@ -40,6 +40,14 @@ func TestValidateFiles(t *testing.T) {
 			"error_invalid_mime_type.txt",
 			"MIME Type Validation Error: files/test/validate/error_invalid_mime_type.txt; Invalid mime type: application/octet-stream;",
 		},
+		"error teleporter without a pair": {
+			"error_teleporter_without_pair.txt",
+			"Stage Map Validation Error: files/test/validate/error_teleporter_without_pair.txt; Place teleporters '=' in pairs;",
+		},
+		"error speed curve": {
+			"error_speed_curve.txt",
+			"Speed Curve Validation Error: files/test/validate/error_speed_curve.speed; Unknown condition: score (line 2);",
+		},
 		"error file does not exist": {
 			"error_file_does_not_exist.txt",
 			"open files/test/validate/error_file_does_not_exist.txt: file does not exist",
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Items of the main menu
+const (
+	menuNewGame int = iota
+	menuContinue
+	menuLevelSelect
+	menuSettings
+	menuQuit
+)
+
+// Items of the settings
+const (
+	settingsMode int = iota
+	settingsLife
+	settingsBack
+)
+
+// Lives can be set from 0 to this number in the settings
+const maxSettingsLife = 5
+
+// menu lists the items under the scene. The items are chosen with j/k and Enter.
+type menu struct {
+	scene  string
+	items  []string
+	cursor int
+}
+
+// Move the cursor with the key. Returns whether the item under the cursor is chosen.
+// q chooses the last item (Quit or Back).
+func (m *menu) handle(ch rune, key termbox.Key) bool {
+	switch {
+	case ch == 'j' || key == termbox.KeyArrowDown:
+		m.cursor = (m.cursor + 1) % len(m.items)
+	case ch == 'k' || key == termbox.KeyArrowUp:
+		m.cursor = (m.cursor + len(m.items) - 1) % len(m.items)
+	case ch == 'q':
+		m.cursor = len(m.items) - 1
+		return true
+	case key == termbox.KeyEnter:
+		return true
+	}
+	return false
+}
+
+// Draw the items under the scene with the buffer and the window like a stage.
+func (m *menu) show() error {
+	y, err := drawScene(m.scene)
+	if err != nil {
+		return err
+	}
+	lines := make([]string, len(m.items))
+	for i, item := range m.items {
+		lines[i] = "  " + item
+		if i == m.cursor {
+			lines[i] = "> " + item
+		}
+	}
+	w := createWindow(createBuffer(strings.NewReader(strings.Join(lines, "\n"))))
+	for i, l := range w.lines {
+		fg := termbox.ColorWhite
+		if i == m.cursor {
+			fg = termbox.ColorGreen | termbox.AttrBold
+		}
+		for x, r := range l.text {
+			screen.setCell(x+3, y+i, r, fg, termbox.ColorBlack)
+		}
+	}
+	return screen.flush()
+}
+
+// Show the menu until an item is chosen, and returns the index of it.
+func (m *menu) choose() (int, error) {
+	for {
+		if err := m.show(); err != nil {
+			return 0, err
+		}
+		ev := screen.pollEvent()
+		if ev.Type == termbox.EventKey && m.handle(ev.Ch, ev.Key) {
+			return m.cursor, nil
+		}
+	}
+}
+
+func mainMenuItems(stages []stage, pr *progress) []string {
+	cont := "Continue"
+	if levelIndex(stages, pr.level) >= 0 {
+		cont += " (level " + strconv.Itoa(pr.level) + ")"
+	}
+	return []string{"New Game", cont, "Level Select", "Settings", "Quit"}
+}
+
+// The level select shows the best score of each stage.
+func levelSelectItems(stages []stage, pr *progress) []string {
+	items := []string{}
+	for _, s := range stages {
+		best := "-"
+		if score, ok := pr.best[s.level]; ok {
+			best = strconv.Itoa(score)
+		}
+		items = append(items, fmt.Sprintf("Level %-3d best: %s", s.level, best))
+	}
+	return append(items, "Back")
+}
+
+func settingsItems(pr *progress) []string {
+	return []string{"Mode: " + gameModeName(pr.gameMode), "Life: " + strconv.Itoa(pr.life), "Back"}
+}
+
+// Change the setting under the cursor to the next value.
+func (pr *progress) changeSetting(item int) {
+	switch item {
+	case settingsMode:
+		pr.gameMode = (pr.gameMode + 1) % len(gameModes)
+	case settingsLife:
+		pr.life = (pr.life + 1) % (maxSettingsLife + 1)
+	}
+}
+
+// Returns the index of the stage of the level, or -1.
+func levelIndex(stages []stage, level int) int {
+	for i, s := range stages {
+		if s.level == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Show the main menu until a game starts. Returns the index of the stage to start from,
+// or false if the player quits.
+func runMenu(stages []stage, pr *progress) (int, bool, error) {
+	top := &menu{scene: sceneStart}
+	for {
+		top.items = mainMenuItems(stages, pr)
+		item, err := top.choose()
+		if err != nil {
+			return 0, false, err
+		}
+		switch item {
+		case menuNewGame:
+			return 0, true, nil
+		case menuContinue:
+			if i := levelIndex(stages, pr.level); i >= 0 {
+				return i, true, nil
+			}
+		case menuLevelSelect:
+			i, err := (&menu{scene: sceneStart, items: levelSelectItems(stages, pr)}).choose()
+			if err != nil {
+				return 0, false, err
+			}
+			if i < len(stages) {
+				return i, true, nil
+			}
+		case menuSettings:
+			settings := &menu{scene: sceneStart}
+			for {
+				settings.items = settingsItems(pr)
+				i, err := settings.choose()
+				if err != nil {
+					return 0, false, err
+				}
+				if i == settingsBack {
+					break
+				}
+				pr.changeSetting(i)
+			}
+		case menuQuit:
+			return 0, false, nil
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestMenu(t *testing.T) {
+	items := []string{"New Game", "Continue", "Level Select", "Settings", "Quit"}
+	cases := map[string]struct {
+		keys     string
+		expected int
+	}{
+		"first item": {"", menuNewGame},
+		"down":       {"j", menuContinue},
+		"up":         {"jjk", menuContinue},
+		"wrap up":    {"k", menuQuit},
+		"wrap down":  {"jjjjj", menuNewGame},
+		"quit":       {"jq", menuQuit},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m := &menu{scene: sceneStart, items: items}
+			chosen := false
+			for _, r := range tt.keys {
+				chosen = m.handle(r, 0)
+			}
+			// Moving the cursor doesn't choose the item, but Enter and q do
+			if !chosen {
+				chosen = m.handle(0, termbox.KeyEnter)
+			}
+			if !chosen {
+				t.Error("expected the item to be chosen")
+			}
+			if m.cursor != tt.expected {
+				t.Errorf("expected %d but %d", tt.expected, m.cursor)
+			}
+		})
+	}
+}
+
+func TestMenuItems(t *testing.T) {
+	stages := []stage{{level: 1}, {level: 2}, {level: 3}}
+	cases := map[string]struct {
+		level     int
+		best      map[int]int
+		continued string
+		selected  []string
+	}{
+		"new": {
+			0, map[int]int{}, "Continue",
+			[]string{"Level 1   best: -", "Level 2   best: -", "Level 3   best: -", "Back"},
+		},
+		"continue": {
+			3, map[int]int{1: 120, 2: 95}, "Continue (level 3)",
+			[]string{"Level 1   best: 120", "Level 2   best: 95", "Level 3   best: -", "Back"},
+		},
+		"unknown level": {
+			9, map[int]int{}, "Continue",
+			[]string{"Level 1   best: -", "Level 2   best: -", "Level 3   best: -", "Back"},
+		},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			pr := newProgress()
+			pr.level, pr.best = tt.level, tt.best
+			if item := mainMenuItems(stages, pr)[menuContinue]; item != tt.continued {
+				t.Errorf("expected %q but %q", tt.continued, item)
+			}
+			selected := levelSelectItems(stages, pr)
+			if len(selected) != len(tt.selected) {
+				t.Fatalf("expected %d but %d", len(tt.selected), len(selected))
+			}
+			for i := range selected {
+				if selected[i] != tt.selected[i] {
+					t.Errorf("expected %q but %q", tt.selected[i], selected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChangeSetting(t *testing.T) {
+	pr := newProgress()
+	pr.changeSetting(settingsMode)
+	if pr.gameMode != timeAttack {
+		t.Errorf("expected %d but %d", timeAttack, pr.gameMode)
+	}
+	pr.changeSetting(settingsMode)
+	pr.changeSetting(settingsMode)
+	if pr.gameMode != normalGame {
+		t.Errorf("expected %d but %d", normalGame, pr.gameMode)
+	}
+	for i := 0; i < maxSettingsLife-1; i++ {
+		pr.changeSetting(settingsLife)
+	}
+	if pr.life != 0 {
+		t.Errorf("expected %d but %d", 0, pr.life)
+	}
+	if items := settingsItems(pr); items[settingsMode] != "Mode: normal" || items[settingsLife] != "Life: 0" {
+		t.Errorf("unexpected settings %v", items)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Game modes
+const (
+	// Eat all apples across the stages
+	normalGame int = iota
+	// Clear each stage as fast as possible
+	timeAttack
+	// Survive as long as possible while apples respawn and enemies multiply
+	survival
+)
+
+const (
+	// An eaten apple respawns every this many ticks in the survival mode
+	survivalAppleTicks = 5
+	// An enemy is added every this many ticks in the survival mode
+	survivalEnemyTicks = 30
+	// Enemies stop multiplying at this number
+	maxSurvivalEnemies = 8
+)
+
+var gameModes = map[string]int{
+	"normal":     normalGame,
+	"timeattack": timeAttack,
+	"survival":   survival,
+}
+
+var gameModeValidationError = errors.New("Game Mode Validation Error")
+
+func gameModeName(m int) string {
+	for name, v := range gameModes {
+		if v == m {
+			return name
+		}
+	}
+	return "normal"
+}
+
+func parseGameMode(name string) (int, error) {
+	if m, ok := gameModes[name]; ok {
+		return m, nil
+	}
+	err := errors.New(name + "; Choose normal, timeattack or survival;")
+	return 0, fmt.Errorf("%w: %+v", gameModeValidationError, err)
+}
+
+// Advance the survival mode by one tick.
+func (s *stage) survive(ps players) {
+	if s.tick%survivalAppleTicks == 0 {
+		s.respawnApple(ps)
+	}
+	if s.tick%survivalEnemyTicks == 0 {
+		s.multiplyEnemies()
+	}
+}
+
+// Put back one of the eaten apples, except under the players and enemies.
+func (s *stage) respawnApple(ps players) {
+	eaten := []point{}
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			cell := getCell(x, y)
+			if cell.Ch == chApple && cell.Fg == termbox.ColorGreen && !ps.isAt(x, y) {
+				eaten = append(eaten, point{x, y})
+			}
+		}
+	}
+	if len(eaten) == 0 {
+		return
+	}
+	a := eaten[random(0, len(eaten)-1)]
+	screen.setCell(a.x, a.y, chApple, termbox.ColorWhite, termbox.ColorBlack)
+}
+
+// Add an enemy of the same kind as one of the enemies at its home.
+func (s *stage) multiplyEnemies() {
+	if len(s.enemies) == 0 || len(s.enemies) >= maxSurvivalEnemies {
+		return
+	}
+	parent := s.enemies[random(0, len(s.enemies)-1)]
+	char, _ := parent.getDisplayFormat()
+	var b iEnemyBuilder
+	switch char {
+	case chHunter:
+		b = s.hunterBuilder
+	case chGhost:
+		b = s.ghostBuilder
+	case chPatrol:
+		b = s.patrolBuilder
+	}
+	if b == nil {
+		return
+	}
+	e := b.build()
+	e.wait(parent.getHome())
+	e.bind(s)
+	s.enemies = append(s.enemies, e)
+}
+
+// Returns the time since the stage started, or the time it took when the stage is over.
+func (p *player) elapsed() time.Duration {
+	if p.state != continuing && p.playTime > 0 {
+		return p.playTime
+	}
+	if p.startedAt.IsZero() {
+		return 0
+	}
+	return time.Since(p.startedAt)
+}
+
+// Returns the timer of the mode shown on the status line.
+func (p *player) gameModeStatus() string {
+	switch p.gameMode {
+	case timeAttack:
+		return " time: " + formatTime(p.elapsed())
+	case survival:
+		return " survived: " + formatTime(p.elapsed())
+	}
+	return ""
+}
+
+func formatTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 1, 64) + "s"
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestParseGameMode(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected int
+		err      error
+	}{
+		"normal":      {"normal", normalGame, nil},
+		"time attack": {"timeattack", timeAttack, nil},
+		"survival":    {"survival", survival, nil},
+		"unknown":     {"hard", normalGame, gameModeValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m, err := parseGameMode(tt.name)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v but %v", tt.err, err)
+			}
+			if m != tt.expected {
+				t.Errorf("expected %d but %d", tt.expected, m)
+			}
+		})
+	}
+}
+
+// Test the stage doesn't end with the apples in the survival mode.
+func TestSurvivalNoWin(t *testing.T) {
+	cases := map[string]struct {
+		gameMode      int
+		expectedState int
+	}{
+		"normal":      {normalGame, win},
+		"time attack": {timeAttack, win},
+		"survival":    {survival, continuing},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x:        7,
+				y:        4,
+				state:    continuing,
+				gameMode: tt.gameMode,
+			}
+			_, offset, err := playerActionTestInit(t, playerTestMapPath+"judge_move_result.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			p.moveCross(-1, 0)
+			if p.state != tt.expectedState {
+				t.Errorf("expected %d but %d", tt.expectedState, p.state)
+			}
+		})
+	}
+}
+
+// Test an eaten apple respawns, but not under the player.
+func TestRespawnApple(t *testing.T) {
+	p := &player{
+		x:     7,
+		y:     4,
+		state: continuing,
+	}
+	s, offset, err := playerActionTestInit(t, playerTestMapPath+"judge_move_result.txt", p)
+	if err != nil {
+		t.Error(err)
+	}
+	p.x += offset
+	appleX, appleY := p.x-1, p.y
+	p.moveCross(-1, 0)
+	s.respawnApple(players{p})
+	if getCell(appleX, appleY).Fg != termbox.ColorGreen {
+		t.Error("expected the apple under the player to stay eaten")
+	}
+	p.moveCross(1, 0)
+	s.respawnApple(players{p})
+	if getCell(appleX, appleY).Fg != termbox.ColorWhite {
+		t.Error("expected the apple to respawn")
+	}
+}
+
+// Test enemies multiply at the homes of the enemies, up to the limit.
+func TestMultiplyEnemies(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter_with_obstacle.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	homeX, homeY := s.enemies[0].getPosition()
+	s.multiplyEnemies()
+	if len(s.enemies) != 2 {
+		t.Fatalf("expected %d but %d", 2, len(s.enemies))
+	}
+	child := s.enemies[1]
+	if x, y := child.getHome(); x != homeX || y != homeY {
+		t.Errorf("expected %d %d but %d %d", homeX, homeY, x, y)
+	}
+	// The new enemy waits until the home is vacant
+	if child.update(p) {
+		t.Error("expected the new enemy to wait while the home is occupied")
+	}
+	s.enemies[0].accelerate(time.Second, 1)
+	s.enemies[0].move(homeX-1, homeY)
+	child.update(p)
+	if !child.update(p) {
+		t.Error("expected the new enemy to appear at the vacant home")
+	}
+	for i := 0; i < maxSurvivalEnemies*2; i++ {
+		s.multiplyEnemies()
+	}
+	if len(s.enemies) != maxSurvivalEnemies {
+		t.Errorf("expected %d but %d", maxSurvivalEnemies, len(s.enemies))
+	}
+}
+
+func TestGameModeStatus(t *testing.T) {
+	cases := map[string]struct {
+		gameMode int
+		expected string
+	}{
+		"normal":      {normalGame, ""},
+		"time attack": {timeAttack, " time: 12.3s"},
+		"survival":    {survival, " survived: 12.3s"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{gameMode: tt.gameMode, state: win, playTime: 12345 * time.Millisecond}
+			if status := p.gameModeStatus(); status != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+// mover is moved on the board by Vim motions.
+// The player and the enemies that mimic Vim share the motions through it.
+type mover interface {
+	getPosition() (int, int)
+	setPosition(x, y int)
+	// Move a cell. Returns false if the mover can't move there.
+	moveOneSquare(x, y int) bool
+}
+
+// probe goes through the board without touching it, to find where a motion leads.
+type probe struct {
+	x int
+	y int
+}
+
+func (pr *probe) getPosition() (int, int) {
+	return pr.x, pr.y
+}
+func (pr *probe) setPosition(x, y int) {
+	pr.x, pr.y = x, y
+}
+func (pr *probe) moveOneSquare(x, y int) bool {
+	if isCharWall(pr.x+x, pr.y+y) {
+		return false
+	}
+	pr.x, pr.y = pr.x+x, pr.y+y
+	return true
+}
+
+// Returns where the motion leads from the cell. The motion of gg is written as 'g'.
+func motionDestination(ch rune, x, y int, s stage) (int, int) {
+	pr := &probe{x: x, y: y}
+	switch ch {
+	case 'w':
+		toBeginningOfNextWord(pr)
+	case 'b':
+		toBeginningPrevWord(pr)
+	case 'e':
+		toEndOfCurrentWord(pr)
+	case '0':
+		toLeftEdge(pr)
+	case '$':
+		toRightEdge(pr)
+	case '^':
+		toBeginningOfFirstWord(pr)
+	case 'g':
+		toFirstLine(pr, s)
+	case 'G':
+		toLastLine(pr, s)
+	}
+	return pr.getPosition()
+}
+
+// w: to the beginning of the next word
+func toBeginningOfNextWord(m mover) bool {
+	spaceFlg := false
+	for {
+		x, y := m.getPosition()
+		if isCharSpace(x, y) || isCharEnemy(x, y) {
+			spaceFlg = true
+		}
+		if !m.moveOneSquare(1, 0) {
+			return false
+		}
+		if spaceFlg {
+			if isCharApple(m.getPosition()) {
+				return true
+			}
+		}
+	}
+}
+
+// b: to the beginning of the previous word
+func toBeginningPrevWord(m mover) bool {
+	isSpace := func() bool {
+		x, y := m.getPosition()
+		return isCharSpace(x-1, y) || isCharEnemy(x-1, y)
+	}
+	for isSpace() {
+		if !m.moveOneSquare(-1, 0) {
+			return false
+		}
+	}
+	for !isSpace() {
+		if !m.moveOneSquare(-1, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// e: to the end of the current word
+func toEndOfCurrentWord(m mover) bool {
+	isSpace := func() bool {
+		x, y := m.getPosition()
+		return isCharSpace(x+1, y) || isCharEnemy(x+1, y)
+	}
+	for isSpace() {
+		if !m.moveOneSquare(1, 0) {
+			return false
+		}
+	}
+	for !isSpace() {
+		if !m.moveOneSquare(1, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// 0: to the beginning of the current line
+func toLeftEdge(m mover) {
+	_, y := m.getPosition()
+	x := 0
+	for {
+		x++
+		if isCharBoundary(x, y) {
+			break
+		}
+	}
+	for {
+		x++
+		if !isCharWall(x, y) {
+			break
+		}
+	}
+	m.setPosition(x, y)
+}
+
+// $: to the end of the current line
+func toRightEdge(m mover) {
+	_, y := m.getPosition()
+	x, _ := screen.size()
+	for {
+		x--
+		if isCharBoundary(x, y) {
+			break
+		}
+	}
+	for {
+		x--
+		if !isCharWall(x, y) {
+			break
+		}
+	}
+	m.setPosition(x, y)
+}
+
+// ^: to the beginning of the first word on the current line
+func toBeginningOfFirstWord(m mover) {
+	toLeftEdge(m)
+	x, y := m.getPosition()
+	for !isCharBoundary(x, y) {
+		if isCharApple(x, y) || isCharPoison(x, y) {
+			m.setPosition(x, y)
+			break
+		}
+		x++
+	}
+}
+
+// gg: to the beginning of the first word on the first line
+func toFirstLine(m mover, s stage) {
+	for y := 1; y < s.height; y++ {
+		if canMove(s, y) {
+			x, _ := m.getPosition()
+			m.setPosition(x, y)
+			toBeginningOfFirstWord(m)
+			break
+		}
+	}
+}
+
+// G: to the beginning of the first word on the last line
+func toLastLine(m mover, s stage) {
+	for y := s.height - 1; y > 0; y-- {
+		if canMove(s, y) {
+			x, _ := m.getPosition()
+			m.setPosition(x, y)
+			toBeginningOfFirstWord(m)
+			break
+		}
+	}
+}
+
+// Ngg or NG: to the beginning of the first word on the selected line
+func toSelectedLine(m mover, s stage, line int) {
+	y := line - 1
+	if y > 0 && y < s.height && canMove(s, y) {
+		x, _ := m.getPosition()
+		m.setPosition(x, y)
+		toBeginningOfFirstWord(m)
+	}
+}
+
+func canMove(s stage, y int) bool {
+	x := getOffset(s.height)
+	for x < s.width {
+		if !isCharWall(x, y) && !isCharEnemy(x, y) {
+			return true
+		}
+		x++
+	}
+	return false
+}
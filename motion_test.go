@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+)
+
+// Test the motions lead an enemy to the same cells as the player.
+func TestMotionDestination(t *testing.T) {
+	cases := map[string]struct {
+		motion    rune
+		expectedX int
+		expectedY int
+	}{
+		"w":  {'w', 6, 1},
+		"e":  {'e', 8, 1},
+		"0":  {'0', 3, 1},
+		"$":  {'$', 13, 1},
+		"gg": {'g', 6, 1},
+		"G":  {'G', 4, 5},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, s, err := enemyActionTestInit(t, enemyTestMapPath+"vim_motion.txt", newEnemyBuilder().defaultHunter())
+			if err != nil {
+				t.Error(err)
+			}
+			x, y := s.enemies[0].getPosition()
+			if rx, ry := motionDestination(tt.motion, x, y, s); rx != tt.expectedX || ry != tt.expectedY {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, rx, ry)
+			}
+			// The board is not changed
+			if !isCharEnemy(x, y) {
+				t.Error("expected the enemy to stay")
+			}
+		})
+	}
+}
@@ -2,36 +2,94 @@ package main
 
 import (
 	"strconv"
+	"strings"
+	"time"
 
 	termbox "github.com/nsf/termbox-go"
 )
 
+const (
+	// Width of the area showing the keys being typed, like 'showcmd' in Vim.
+	showCmdWidth = 10
+	// Number of moves that can be rewound with u.
+	maxHistory = 10
+	// Points lost each time a move is rewound with u.
+	undoPenalty = 1
+)
+
 type player struct {
 	x           int
 	y           int
+	dirX        int
+	dirY        int
 	inputNum    int
 	inputG      bool
 	score       int
 	targetScore int
+	bonus       int
+	energized   bool
 	state       int
+	lastCommand command
+	history     []move
+	eaten       []point
+	keymap      keymap
+	pending     []rune
+	trail       *trail
+	// Remaining ticks of the power-ups
+	frozen int
+	slowed int
+	// The shield absorbs the next hit of an enemy
+	shield     bool
+	extraLives int
+	// Tiles of the stage
+	teleporters map[point]point
+	doors       []point
+	// Motions used in the stage, checked by lessons
+	used map[string]bool
+	// Shown instead of the mode until the next key, e.g. when a key is not allowed
+	warning string
+	// Register and operator waiting for a motion, e.g. "a and 3d.
+	// The stage can't be edited, so the motion of an operator only moves the cursor.
+	register []rune
+	operator []rune
+	opNum    int
+	// Game mode and the time of the stage
+	gameMode  int
+	startedAt time.Time
+	playTime  time.Duration
+	// Keys typed in the stage
+	keystrokes int
+	// 1, 2, ... when several players share the stage (0 alone), and all the players on the stage
+	number int
+	team   players
 }
 
-func (p *player) control(s stage) error {
-	switch ev := termbox.PollEvent(); ev.Type {
-	case termbox.EventKey:
-		if v, ok := p.isInputNum(ev.Ch); ok {
-			p.inputNum, _ = strconv.Atoi(strconv.Itoa(p.inputNum) + v)
-			p.inputG = false
-		} else {
-			p.action(ev.Ch, s)
-			termbox.SetCursor(p.x, p.y)
-			p.plotScore(s)
-			if err := termbox.Flush(); err != nil {
-				return err
-			}
-		}
+// command is the last motion with its count, repeated by '.'.
+type command struct {
+	ch     rune
+	num    int
+	inputG bool
+}
+
+// move is the position before a motion and the apples eaten by it, rewound by 'u'.
+type move struct {
+	x     int
+	y     int
+	eaten []point
+}
+
+type point struct {
+	x int
+	y int
+}
+
+func (p *player) input(ch rune, s stage) {
+	if v, ok := p.isInputNum(ch); ok && s.allowsCounts() {
+		p.inputNum, _ = strconv.Atoi(strconv.Itoa(p.inputNum) + v)
+		p.inputG = false
+		return
 	}
-	return nil
+	p.action(ch, s)
 }
 func (p *player) isInputNum(r rune) (string, bool) {
 	s := string(r)
@@ -43,6 +101,103 @@ func (p *player) isInputNum(r rune) (string, bool) {
 }
 
 func (p *player) action(ch rune, s stage) {
+	if !s.isAllowed(ch) {
+		p.forbid(ch, s)
+		return
+	}
+	p.warning = ""
+	// The name of the register follows '"'
+	if len(p.register) == 1 {
+		p.register = append(p.register, ch)
+		return
+	}
+	switch ch {
+	// repeat the last motion
+	case '.':
+		p.repeat(s)
+		return
+	// rewind the last motion
+	case 'u':
+		p.undo(s)
+		return
+	// wait for the name of the register
+	case '"':
+		if len(p.operator) == 0 && p.inputNum == 0 && !p.inputG {
+			p.register = []rune{ch}
+			return
+		}
+	// wait for the motion of the operator
+	case 'd', 'y', 'c':
+		if len(p.operator) == 0 && !p.inputG {
+			p.pendOperator(ch)
+			return
+		}
+		// dd, yy and cc work on the current line, so the cursor stays
+		p.initInput()
+		return
+	}
+	// The count of the operator and the count of the motion are multiplied like Vim, e.g. 2d3w moves 6 words
+	if p.opNum != 0 && p.inputNum != 0 {
+		p.inputNum *= p.opNum
+	} else if p.opNum != 0 {
+		p.inputNum = p.opNum
+	}
+
+	cmd := command{ch: ch, num: p.inputNum, inputG: p.inputG}
+	m := move{x: p.x, y: p.y}
+	p.eaten = nil
+	p.motion(ch, s)
+	if p.x != m.x || p.y != m.y {
+		p.dirX, p.dirY = direction(p.x-m.x, p.y-m.y)
+	}
+	if p.x != m.x || p.y != m.y || len(p.eaten) > 0 {
+		m.eaten = p.eaten
+		p.history = append(p.history, m)
+		if len(p.history) > maxHistory {
+			p.history = p.history[1:]
+		}
+	}
+	if isRepeatable(ch) && !(ch == 'g' && !cmd.inputG) {
+		p.lastCommand = cmd
+		p.use(cmd)
+	}
+}
+
+// The operator takes the count typed before it, and waits for the motion.
+func (p *player) pendOperator(ch rune) {
+	if p.inputNum != 0 {
+		p.operator = []rune(strconv.Itoa(p.inputNum))
+	}
+	p.operator = append(p.operator, ch)
+	p.opNum = p.inputNum
+	p.inputNum = 0
+}
+
+// Returns the unit vector of the main direction of the movement.
+func direction(dx, dy int) (int, int) {
+	sign := func(i int) int {
+		if i > 0 {
+			return 1
+		} else if i < 0 {
+			return -1
+		}
+		return 0
+	}
+	if dx*dx >= dy*dy {
+		return sign(dx), 0
+	}
+	return 0, sign(dy)
+}
+
+func isRepeatable(ch rune) bool {
+	switch ch {
+	case 'k', 'j', 'h', 'l', 'w', 'b', 'e', '0', '$', '^', 'g', 'G':
+		return true
+	}
+	return false
+}
+
+func (p *player) motion(ch rune, s stage) {
 	// Move cursor
 	switch ch {
 	// to upward direction by one line
@@ -89,6 +244,42 @@ func (p *player) action(ch rune, s stage) {
 	}
 }
 
+// .: Repeat the last motion (a count typed before '.' replaces the original count)
+func (p *player) repeat(s stage) {
+	c := p.lastCommand
+	if c.ch == 0 {
+		p.initInput()
+		return
+	}
+	if p.inputNum != 0 {
+		c.num = p.inputNum
+	}
+	p.inputNum, p.inputG = c.num, c.inputG
+	p.action(c.ch, s)
+}
+
+// u: Rewind the last motion and put back the apples eaten by it
+// The penalty is taken from the bonus, because the score counts the apples left to win.
+func (p *player) undo(s stage) {
+	p.initInput()
+	if len(p.history) == 0 {
+		return
+	}
+	m := p.history[len(p.history)-1]
+	p.history = p.history[:len(p.history)-1]
+	apple := termbox.Cell{Ch: chApple, Fg: termbox.ColorWhite, Bg: termbox.ColorBlack}
+	for _, a := range m.eaten {
+		// An enemy on the apple puts it back when it moves off
+		if !s.putUnderEnemy(a.x, a.y, apple) {
+			screen.setCell(a.x, a.y, apple.Ch, apple.Fg, apple.Bg)
+		}
+		p.score--
+	}
+	p.x, p.y = m.x, m.y
+	p.bonus -= undoPenalty
+	p.judgeMoveResult()
+}
+
 func (p *player) moveCross(x, y int) {
 	if p.inputNum != 0 && p.inputG {
 		p.initInput()
@@ -107,7 +298,7 @@ func (p *player) moveCross(x, y int) {
 func (p *player) moveOneSquare(x, y int) bool {
 	tmpX := p.x + x
 	tmpY := p.y + y
-	if !isCharWall(tmpX, tmpY) {
+	if !isCharWall(tmpX, tmpY) && !isOneWayBlocked(getCell(p.x, p.y).Ch, getCell(tmpX, tmpY).Ch, x, y) {
 		p.x = tmpX
 		p.y = tmpY
 	} else {
@@ -134,7 +325,9 @@ func (p *player) moveByWord(fn func() bool) {
 }
 
 func (p *player) jumpOnCurrentLine(fn func()) {
+	from := point{p.x, p.y}
 	fn()
+	p.jumpTrail(from)
 	p.judgeMoveResult()
 	p.initInput()
 }
@@ -143,6 +336,7 @@ func (p *player) jumpAcrossLine(fn func(stage), s stage, ch rune) {
 	if ch == 'g' && !p.inputG {
 		p.inputG = true
 	} else if ch == 'G' || (ch == 'g' && p.inputG) {
+		from := point{p.x, p.y}
 		if p.inputNum == 0 {
 			// to the beginning of the first word on the first or last line
 			fn(s)
@@ -150,172 +344,193 @@ func (p *player) jumpAcrossLine(fn func(stage), s stage, ch rune) {
 			// to the beginning of the first word on the selected line
 			p.toSelectedLine(s)
 		}
+		p.jumpTrail(from)
 		p.judgeMoveResult()
 		p.initInput()
 	}
 }
 
 func (p *player) judgeMoveResult() {
-	if isCharEnemy(p.x, p.y) || isCharPoison(p.x, p.y) {
+	if dest, ok := p.teleporters[point{p.x, p.y}]; ok {
+		// Go out of the paired teleporter
+		p.trail.add(p.x, p.y)
+		p.x, p.y = dest.x, dest.y
+	}
+	// Enemies check the cells the player has reached in their next tick
+	p.trail.add(p.x, p.y)
+	// With the shield, the enemy is sent home when the stage checks the collision
+	if (isCharEnemy(p.x, p.y) && !isCharFrightened(p.x, p.y) && !p.shield) || isCharPoison(p.x, p.y) {
 		p.state = lose
 	} else {
 		// Change target color (white → green)
-		winWidth, _ := termbox.Size()
-		cell := termbox.CellBuffer()[(winWidth*p.y)+p.x]
+		winWidth, _ := screen.size()
+		cell := screen.cellBuffer()[(winWidth*p.y)+p.x]
+		if cell.Ch == chPellet && cell.Fg == termbox.ColorWhite {
+			// Enemies are frightened on the next move
+			screen.setCell(p.x, p.y, cell.Ch, termbox.ColorGreen, termbox.ColorBlack)
+			p.energized = true
+		}
+		if cell.Ch == chKey && cell.Fg == termbox.ColorWhite {
+			screen.setCell(p.x, p.y, cell.Ch, termbox.ColorGreen, termbox.ColorBlack)
+			p.openDoors()
+		}
+		if isCharPowerUp(p.x, p.y) && cell.Fg == termbox.ColorWhite {
+			screen.setCell(p.x, p.y, cell.Ch, termbox.ColorGreen, termbox.ColorBlack)
+			p.pickUp(cell.Ch)
+		}
 		if cell.Ch == chApple && cell.Fg == termbox.ColorWhite {
-			termbox.SetCell(p.x, p.y, cell.Ch, termbox.ColorGreen, termbox.ColorBlack)
+			screen.setCell(p.x, p.y, cell.Ch, termbox.ColorGreen, termbox.ColorBlack)
+			p.eaten = append(p.eaten, point{p.x, p.y})
 			p.score++
-			if p.score == p.targetScore {
+			// Apples respawn in the survival mode, so the stage never ends with them
+			if p.applesEaten() == p.targetScore && p.gameMode != survival {
 				p.state = win
 			}
 		}
 	}
 }
 
+// Enemies catch the player on the cells a jump passes over, as well as on the cell it lands on.
+// A jump against a one-way tile on the way doesn't move the player.
+func (p *player) jumpTrail(from point) {
+	if isJumpBlocked(from, point{p.x, p.y}) {
+		p.x, p.y = from.x, from.y
+	}
+	if p.x != from.x || p.y != from.y {
+		p.trail.jump(from, p.x, p.y)
+	}
+}
+
 func (p *player) initInput() {
 	p.inputNum = 0
 	p.inputG = false
+	p.register = nil
+	p.operator = nil
+	p.opNum = 0
 }
 
 // w: Move cursor to the beginning of the next word
 func (p *player) toBeginningOfNextWord() bool {
-	spaceFlg := false
-	for {
-		if isCharSpace(p.x, p.y) || isCharEnemy(p.x, p.y) {
-			spaceFlg = true
-		}
-		if !p.moveOneSquare(1, 0) {
-			return false
-		}
-		if spaceFlg {
-			if isCharApple(p.x, p.y) {
-				return true
-			}
-		}
-	}
+	return toBeginningOfNextWord(p)
 }
 
 // b: Move cursor to the beginning of the previous word
 func (p *player) toBeginningPrevWord() bool {
-	for isCharSpace(p.x-1, p.y) || isCharEnemy(p.x-1, p.y) {
-		p.moveOneSquare(-1, 0)
-	}
-	for !isCharSpace(p.x-1, p.y) && !isCharEnemy(p.x-1, p.y) {
-		if !p.moveOneSquare(-1, 0) {
-			return false
-		}
-	}
-	return true
+	return toBeginningPrevWord(p)
 }
 
 // e: Move cursor to the end of the current word
 func (p *player) toEndOfCurrentWord() bool {
-	for isCharSpace(p.x+1, p.y) || isCharEnemy(p.x+1, p.y) {
-		p.moveOneSquare(1, 0)
-	}
-	for !isCharSpace(p.x+1, p.y) && !isCharEnemy(p.x+1, p.y) {
-		if !p.moveOneSquare(1, 0) {
-			return false
-		}
-	}
-	return true
+	return toEndOfCurrentWord(p)
 }
 
 // 0: Move cursor to the beginning of the current line
 func (p *player) toLeftEdge() {
-	x := 0
-	for {
-		x++
-		if isCharBoundary(x, p.y) {
-			break
-		}
-	}
-	for {
-		x++
-		if !isCharWall(x, p.y) {
-			break
-		}
-	}
-	p.x = x
+	toLeftEdge(p)
 }
 
 // $: Move cursor to the end of the current line
 func (p *player) toRightEdge() {
-	x, _ := termbox.Size()
-	for {
-		x--
-		if isCharBoundary(x, p.y) {
-			break
-		}
-	}
-	for {
-		x--
-		if !isCharWall(x, p.y) {
-			break
-		}
-	}
-	p.x = x
+	toRightEdge(p)
 }
 
 // ^: Move cursor to the beginning of the first word on the current line
 func (p *player) toBeginningOfFirstWord() {
-	p.toLeftEdge()
-	x := p.x
-	for !isCharBoundary(x, p.y) {
-		if isCharApple(x, p.y) || isCharPoison(x, p.y) {
-			p.x = x
-			break
-		}
-		x++
-	}
+	toBeginningOfFirstWord(p)
 }
 
 // gg: Move cursor to the beginning of the first word on the first line
 func (p *player) toFirstLine(s stage) {
-	for y := 1; y < s.height; y++ {
-		if canMove(s, y) {
-			p.y = y
-			p.toBeginningOfFirstWord()
-			break
-		}
-	}
+	toFirstLine(p, s)
 }
 
 // G: Move cursor to the beginning of the first word on the last line
 func (p *player) toLastLine(s stage) {
-	for y := s.height - 1; y > 0; y-- {
-		if canMove(s, y) {
-			p.y = y
-			p.toBeginningOfFirstWord()
-			break
-		}
-	}
+	toLastLine(p, s)
 }
 
 // Ngg or NG: Move cursor to the beginning of the first word on the selected line
 func (p *player) toSelectedLine(s stage) {
-	y := p.inputNum - 1
-	if y > 0 && y < s.height && canMove(s, y) {
-		p.y = y
-		p.toBeginningOfFirstWord()
-	}
+	toSelectedLine(p, s, p.inputNum)
 }
 
-func canMove(s stage, y int) bool {
-	x := getOffset(s.height)
-	for x < s.width {
-		if !isCharWall(x, y) && !isCharEnemy(x, y) {
-			return true
-		}
-		x++
-	}
-	return false
+func (p *player) getPosition() (int, int) {
+	return p.x, p.y
+}
+func (p *player) setPosition(x, y int) {
+	p.x, p.y = x, y
 }
 
 func (p *player) plotScore(s stage) {
 	position := s.height
 	text := []rune("score: " + strconv.Itoa(p.score) + "/" + strconv.Itoa(p.targetScore))
+	// Each of the two players has a line
+	if p.number > 0 {
+		position += p.number - 1
+		text = append([]rune(strconv.Itoa(p.number)+"P "), text...)
+	}
+	if p.bonus != 0 {
+		text = append(text, []rune(" bonus: "+strconv.Itoa(p.bonus))...)
+	}
+	text = append(text, []rune(p.powerUpStatus())...)
+	text = append(text, []rune(p.gameModeStatus())...)
+	if r := s.lesson.remaining(p); len(r) > 0 {
+		text = append(text, []rune(" use: "+strings.Join(r, " "))...)
+	}
+	winWidth, _ := screen.size()
+	for x := 0; x < winWidth; x++ {
+		screen.setCell(x, position, chSpace, termbox.ColorGreen, termbox.ColorBlack)
+	}
 	for x, r := range text {
-		termbox.SetCell(x, position, r, termbox.ColorGreen, termbox.ColorBlack)
+		screen.setCell(x, position, r, termbox.ColorGreen, termbox.ColorBlack)
+	}
+	mode, color := []rune(p.mode()), termbox.ColorWhite|termbox.AttrBold
+	if p.warning != "" {
+		mode, color = []rune(p.warning), termbox.ColorRed|termbox.AttrBold
+	}
+	modeX := len(text) + 2
+	for x, r := range mode {
+		screen.setCell(modeX+x, position, r, color, termbox.ColorBlack)
+	}
+	// Show the keys being typed at the right edge of the stage like Vim
+	showCmdX := s.width - showCmdWidth
+	if showCmdX < modeX+len(mode)+1 {
+		showCmdX = modeX + len(mode) + 1
+	}
+	for x, r := range p.showCmd() {
+		screen.setCell(showCmdX+x, position, r, termbox.ColorWhite, termbox.ColorBlack)
+	}
+}
+
+// The stage can't be edited, so the player stays in the normal mode.
+// An operator waiting for its motion is shown by showCmd like Vim.
+func (p *player) mode() string {
+	return "-- NORMAL --"
+}
+
+// Returns the register, the operator, the count, the g prefix and the keys waiting for a mapping.
+// These are cleared by initInput or when the mapping is resolved.
+func (p *player) showCmd() []rune {
+	cmd := append([]rune{}, p.register...)
+	cmd = append(cmd, p.operator...)
+	if p.inputNum != 0 {
+		cmd = append(cmd, []rune(strconv.Itoa(p.inputNum))...)
+	}
+	if p.inputG {
+		cmd = append(cmd, 'g')
+	}
+	for _, r := range p.pending {
+		if r == chSpace {
+			cmd = append(cmd, []rune("<20>")...)
+		} else if r == chEsc {
+			cmd = append(cmd, '^', '[')
+		} else {
+			cmd = append(cmd, r)
+		}
+	}
+	// Like Vim, only the last keys are shown when they don't fit
+	if len(cmd) > showCmdWidth {
+		cmd = cmd[len(cmd)-showCmdWidth:]
 	}
+	return cmd
 }
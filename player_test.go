@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"strconv"
+	"strings"
 	"testing"
 
 	termbox "github.com/nsf/termbox-go"
@@ -349,6 +351,7 @@ func playerActionTestInit(t *testing.T, mapPath string, p *player) (stage, int,
 		mapPath:       mapPath,
 		hunterBuilder: newEnemyBuilder().defaultHunter(),
 		ghostBuilder:  newEnemyBuilder().defaultGhost(),
+		patrolBuilder: newEnemyBuilder().defaultPatrol(),
 	}
 	f, err := static.ReadFile(s.mapPath)
 	if err != nil {
@@ -359,6 +362,274 @@ func playerActionTestInit(t *testing.T, mapPath string, p *player) (stage, int,
 	if err = w.show(b); err != nil {
 		return s, b.offset, err
 	}
-	s.plot(b, p)
+	s.plot(b, players{p})
 	return s, b.offset, nil
 }
+
+func TestRepeat(t *testing.T) {
+	const initX, initY = 7, 5
+	cases := map[string]struct {
+		inputs    string
+		expectedX int
+		expectedY int
+	}{
+		"repeat":                     {"2l.", initX + 4, initY},
+		"repeat with new count":      {"2l1.", initX + 3, initY},
+		"repeat the last motion":     {"jl.", initX + 2, initY + 1},
+		"repeat without last motion": {".", initX, initY},
+		"repeat gg":                  {"jgg.", 1, 1},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x: initX,
+				y: initY,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			tt.expectedX += offset
+			for _, r := range tt.inputs {
+				if v, ok := p.isInputNum(r); ok {
+					p.inputNum, _ = strconv.Atoi(strconv.Itoa(p.inputNum) + v)
+					continue
+				}
+				p.action(r, s)
+			}
+			if !(p.x == tt.expectedX && p.y == tt.expectedY) {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, p.x, p.y)
+			}
+		})
+	}
+}
+
+// Test the mode is shown after the score, and a warning takes its place.
+func TestPlotMode(t *testing.T) {
+	cases := map[string]struct {
+		warning  string
+		expected string
+	}{
+		"normal":  {"", "-- NORMAL --"},
+		"warning": {"'w' is not allowed", "'w' is not allowed"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{x: 7, y: 5}
+			s, _, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.warning = tt.warning
+			p.plotScore(s)
+			line := ""
+			winWidth, _ := screen.size()
+			for x := 0; x < winWidth; x++ {
+				line += string(getCell(x, s.height).Ch)
+			}
+			if !strings.Contains(line, tt.expected) {
+				t.Errorf("expected %q in %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestOperator(t *testing.T) {
+	const initX, initY = 7, 5
+	cases := map[string]struct {
+		inputs      string
+		expectedX   int
+		expectedCmd string
+	}{
+		"operator and motion":    {"dl", initX + 1, ""},
+		"count of the operator":  {"3dh", initX - 3, ""},
+		"counts are multiplied":  {"2d2h", initX - 4, ""},
+		"register":               {"\"a2l", initX + 2, ""},
+		"operator on the line":   {"ddl", initX + 1, ""},
+		"waiting for the motion": {"\"ay3", initX, "\"ay3"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x: initX,
+				y: initY,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			tt.expectedX += offset
+			for _, r := range tt.inputs {
+				p.input(r, s)
+			}
+			if p.x != tt.expectedX || p.y != initY {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, initY, p.x, p.y)
+			}
+			if cmd := string(p.showCmd()); cmd != tt.expectedCmd {
+				t.Errorf("expected %q but %q", tt.expectedCmd, cmd)
+			}
+		})
+	}
+}
+
+func TestUndo(t *testing.T) {
+	const initX, initY = 14, 1
+	cases := map[string]struct {
+		inputs        string
+		expectedX     int
+		expectedY     int
+		expectedScore int
+		expectedBonus int
+	}{
+		"undo":               {"wu", initX, initY, 0, -undoPenalty},
+		"undo twice":         {"wwuu", initX, initY, 0, -undoPenalty * 2},
+		"undo the last move": {"wwu", 16, initY, 1, -undoPenalty},
+		"undo without moves": {"u", initX, initY, 0, 0},
+		"repeat after undo":  {"wu.", 16, initY, 1, -undoPenalty},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x:     initX,
+				y:     initY,
+				state: continuing,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_by_word.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			tt.expectedX += offset
+			for _, r := range tt.inputs {
+				p.action(r, s)
+			}
+			if !(p.x == tt.expectedX && p.y == tt.expectedY) {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, p.x, p.y)
+			}
+			if p.score != tt.expectedScore || p.bonus != tt.expectedBonus {
+				t.Errorf("expected %d %d but %d %d", tt.expectedScore, tt.expectedBonus, p.score, p.bonus)
+			}
+		})
+	}
+}
+
+// An apple put back under an enemy appears when the enemy moves off, so the stage can still be won.
+func TestUndoUnderEnemy(t *testing.T) {
+	const initX, initY = 14, 1
+	p := &player{
+		x:     initX,
+		y:     initY,
+		state: continuing,
+	}
+	s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_by_word.txt", p)
+	if err != nil {
+		t.Error(err)
+	}
+	p.x += offset
+	appleX := 16 + offset
+	p.action('w', s)
+	p.action('h', s)
+
+	// An enemy steps on the eaten apple
+	e := newEnemyBuilder().defaultHunter().build()
+	e.wait(appleX+1, initY)
+	e.(*enemy).respawn()
+	e.(*enemy).progress = stepCost
+	e.move(appleX, initY)
+	s.enemies = append(s.enemies, e)
+
+	p.action('u', s)
+	p.action('u', s)
+	if !isCharHunter(appleX, initY) {
+		t.Errorf("expected %q but %q", chHunter, getCell(appleX, initY).Ch)
+	}
+	e.(*enemy).progress = stepCost
+	e.move(appleX+1, initY)
+	if cell := getCell(appleX, initY); cell.Ch != chApple || cell.Fg != termbox.ColorWhite {
+		t.Errorf("expected %q %d but %q %d", chApple, termbox.ColorWhite, cell.Ch, cell.Fg)
+	}
+	if p.score != 0 {
+		t.Errorf("expected %d but %d", 0, p.score)
+	}
+}
+
+func TestShowCmd(t *testing.T) {
+	cases := map[string]struct {
+		player   player
+		expected string
+	}{
+		"nothing":         {player{}, ""},
+		"count":           {player{inputNum: 12}, "12"},
+		"g prefix":        {player{inputG: true}, "g"},
+		"count and g":     {player{inputNum: 3, inputG: true}, "3g"},
+		"pending keys":    {player{pending: []rune{chSpace, 'x'}}, "<20>x"},
+		"too many keys":   {player{inputNum: 1234567, inputG: true, pending: []rune{'a', 'b', 'c'}}, "234567gabc"},
+		"escape sequence": {player{pending: []rune{chEsc}}, "^["},
+		"register":        {player{register: []rune{'"', 'a'}}, "\"a"},
+		"operator":        {player{register: []rune{'"', 'a'}, operator: []rune("3d"), inputNum: 2}, "\"a3d2"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if result := string(tt.player.showCmd()); result != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDirection(t *testing.T) {
+	cases := map[string]struct {
+		dx, dy               int
+		expectedX, expectedY int
+	}{
+		"stay":         {0, 0, 0, 0},
+		"left":         {-3, 0, -1, 0},
+		"down":         {0, 2, 0, 1},
+		"mostly up":    {1, -4, 0, -1},
+		"mostly right": {5, 2, 1, 0},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			x, y := direction(tt.dx, tt.dy)
+			if x != tt.expectedX || y != tt.expectedY {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, x, y)
+			}
+		})
+	}
+}
+
+func TestEatPellet(t *testing.T) {
+	p := &player{
+		x:     1,
+		y:     1,
+		state: continuing,
+	}
+	s, offset, err := playerActionTestInit(t, playerTestMapPath+"pellet.txt", p)
+	if err != nil {
+		t.Error(err)
+	}
+	p.x += offset
+	p.inputNum = 2
+	p.moveCross(1, 0)
+	// The pellet is not an apple
+	if p.score != 1 || !p.energized {
+		t.Errorf("expected %d %t but %d %t", 1, true, p.score, p.energized)
+	}
+	// The player can step on a frightened enemy
+	s.enemies[0].frighten(defaultFrightenedTime)
+	p.inputNum = 2
+	p.moveCross(1, 0)
+	if p.state != continuing {
+		t.Errorf("expected %d but %d", continuing, p.state)
+	}
+}
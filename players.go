@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Ways to play with the players on the stage
+const (
+	onePlayer int = iota
+	// The players eat the apples together
+	coop
+	// The players race for the apples
+	versus
+)
+
+// The players except the one shown by the cursor are highlighted with this color
+const colorOtherPlayer = termbox.ColorCyan
+
+var twoPlayerModes = map[string]int{
+	"coop":   coop,
+	"versus": versus,
+}
+
+var twoPlayerValidationError = errors.New("Two Player Validation Error")
+
+func parseTwoPlayerMode(name string) (int, error) {
+	if m, ok := twoPlayerModes[name]; ok {
+		return m, nil
+	}
+	err := errors.New(name + "; Choose coop or versus;")
+	return onePlayer, fmt.Errorf("%w: %+v", twoPlayerValidationError, err)
+}
+
+// Keys of the second player, who shares the keyboard with the first player using the Vim keys.
+var secondPlayerKeys = map[termbox.Key][]rune{
+	termbox.KeyArrowUp:    {'k'},
+	termbox.KeyArrowDown:  {'j'},
+	termbox.KeyArrowLeft:  {'h'},
+	termbox.KeyArrowRight: {'l'},
+	termbox.KeyHome:       {'0'},
+	termbox.KeyEnd:        {'$'},
+	termbox.KeyPgup:       {'g', 'g'},
+	termbox.KeyPgdn:       {'G'},
+}
+
+// players are the players on the stage. The first player is shown by the cursor.
+type players []*player
+
+// Returns the players of a stage, alone or two on one keyboard.
+func newPlayers(km keymap, mode int) players {
+	if mode == onePlayer {
+		return players{{keymap: km}}
+	}
+	return newTeam(2, km)
+}
+
+// Returns n players sharing the apples and the enemies of a stage.
+// The keymap is of the first player.
+func newTeam(n int, km keymap) players {
+	ps := players{}
+	for i := 1; i <= n; i++ {
+		ps = append(ps, &player{number: i})
+	}
+	ps[0].keymap = km
+	for _, p := range ps {
+		p.team = ps
+	}
+	return ps
+}
+
+// The stage goes on until a player wins or quits, or all the players are caught.
+func (ps players) continuing() bool {
+	for _, p := range ps {
+		if p.state == win || p.state == quit {
+			return false
+		}
+	}
+	for _, p := range ps {
+		if p.state == continuing {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the result of the stage: quit, win or lose.
+func (ps players) state() int {
+	state := lose
+	for _, p := range ps {
+		switch p.state {
+		case quit:
+			return quit
+		case win:
+			state = win
+		}
+	}
+	return state
+}
+
+// The players and the enemies change the cells in their own goroutines.
+// Each of them holds the lock while it changes the cells and flushes the screen,
+// so that a move is never made over the players highlighted for the flush.
+var boardMu sync.Mutex
+
+func (ps players) control(s stage) error {
+	switch ev := screen.pollEvent(); ev.Type {
+	case termbox.EventKey:
+		boardMu.Lock()
+		defer boardMu.Unlock()
+		for _, p := range ps {
+			if p.state == continuing {
+				p.handle(ev, s)
+			}
+		}
+		for _, p := range ps {
+			p.plotScore(s)
+		}
+		if err := ps.flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the player the enemy goes after, i.e. the nearest player still on the stage.
+func (ps players) target(e iEnemy) *player {
+	x, y := e.getPosition()
+	target, distance := ps[0], math.Inf(1)
+	for _, p := range ps {
+		if p.state != continuing {
+			continue
+		}
+		if d := math.Hypot(float64(p.x-x), float64(p.y-y)); d < distance {
+			target, distance = p, d
+		}
+	}
+	return target
+}
+
+// Whether a player is at the cell.
+func (ps players) isAt(x, y int) bool {
+	for _, p := range ps {
+		if p.x == x && p.y == y {
+			return true
+		}
+	}
+	return false
+}
+
+// playerScreen is a screen that shows each player its own cursor, e.g. the screen of the clients.
+type playerScreen interface {
+	setPlayerCursor(number, x, y int, visible bool)
+}
+
+// Flush the screen with the players. The first player is the cursor, and the other players
+// are highlighted only while the screen is flushed, so that the cells keep the state of the stage.
+// The caller holds boardMu while the game is running.
+func (ps players) flush() error {
+	others := ps[1:]
+	if s, ok := screen.(playerScreen); ok {
+		// Each player sees its own cursor among the others
+		for _, p := range ps {
+			s.setPlayerCursor(p.number, p.x, p.y, p.state == continuing)
+		}
+		others = ps
+	} else if p1 := ps[0]; p1.number == 0 || p1.state == continuing {
+		screen.setCursor(p1.x, p1.y)
+	} else {
+		screen.hideCursor()
+	}
+	highlighted := []*player{}
+	cells := []termbox.Cell{}
+	for _, p := range others {
+		if p.state != continuing {
+			continue
+		}
+		cell := getCell(p.x, p.y)
+		screen.setCell(p.x, p.y, cell.Ch, cell.Fg, colorOtherPlayer)
+		highlighted = append(highlighted, p)
+		cells = append(cells, cell)
+	}
+	err := screen.flush()
+	for i, p := range highlighted {
+		screen.setCell(p.x, p.y, cells[i].Ch, cells[i].Fg, cells[i].Bg)
+	}
+	return err
+}
+
+// Returns the points of the players shown after the stage, e.g. "1P wins! 23 - 19".
+func (ps players) result(mode int) string {
+	if mode == onePlayer || len(ps) < 2 {
+		return ""
+	}
+	points := []string{}
+	best, winner := 0, 0
+	for _, p := range ps {
+		points = append(points, strconv.Itoa(p.points()))
+		if p.points() > best || winner == 0 {
+			best, winner = p.points(), p.number
+		} else if p.points() == best {
+			// Nobody wins with the same points
+			winner = -1
+		}
+	}
+	switch {
+	case mode == coop:
+		labels := []string{}
+		for i, p := range ps {
+			labels = append(labels, strconv.Itoa(p.number)+"P: "+points[i])
+		}
+		return strings.Join(labels, "  ")
+	case winner > 0:
+		return strconv.Itoa(winner) + "P wins! " + strings.Join(points, " - ")
+	}
+	return "Draw! " + strings.Join(points, " - ")
+}
+
+// Handle the key event if it is one of the keys of the player.
+func (p *player) handle(ev event, s stage) {
+	ch, ok := p.keysOf(ev)
+	if !ok {
+		return
+	}
+	p.keystrokes++
+	// Only the first player has the mappings of pacvimrc
+	var keys []rune
+	keys, p.pending = p.keymap.apply(append(p.pending, ch...))
+	for _, k := range keys {
+		p.input(k, s)
+	}
+}
+
+// Returns the keys of the event typed by the player.
+func (p *player) keysOf(ev event) ([]rune, bool) {
+	if ev.player == 0 {
+		// The second player shares the keyboard with the first player
+		keys, second := secondPlayerKeys[ev.Key]
+		if p.number == 2 {
+			return keys, second
+		}
+		if p.number == 1 && second {
+			return nil, false
+		}
+	} else if ev.player != p.number {
+		// The key is typed on the keyboard of another player
+		return nil, false
+	}
+	ch := ev.Ch
+	switch ev.Key {
+	case termbox.KeySpace:
+		ch = chSpace
+	case termbox.KeyEsc:
+		ch = chEsc
+	}
+	return []rune{ch}, true
+}
+
+// Apples eaten by the players on the stage.
+func (p *player) applesEaten() int {
+	eaten := 0
+	for _, q := range p.teammates() {
+		eaten += q.score
+	}
+	return eaten
+}
+
+// Returns the players on the stage with the player.
+func (p *player) teammates() players {
+	if p.team == nil {
+		return players{p}
+	}
+	return p.team
+}
+
+func (p *player) points() int {
+	return p.score + p.bonus
+}
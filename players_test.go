@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestParseTwoPlayerMode(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected int
+		err      error
+	}{
+		"coop":    {"coop", coop, nil},
+		"versus":  {"versus", versus, nil},
+		"unknown": {"battle", onePlayer, twoPlayerValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			m, err := parseTwoPlayerMode(tt.name)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v but %v", tt.err, err)
+			}
+			if m != tt.expected {
+				t.Errorf("expected %d but %d", tt.expected, m)
+			}
+		})
+	}
+}
+
+// Test the keys are shared between the players on one keyboard.
+func TestKeysOf(t *testing.T) {
+	cases := map[string]struct {
+		number   int
+		ev       event
+		expected string
+		ok       bool
+	}{
+		"alone":                {0, event{Event: termbox.Event{Ch: 'h'}}, "h", true},
+		"alone with space":     {0, event{Event: termbox.Event{Key: termbox.KeySpace}}, " ", true},
+		"first player":         {1, event{Event: termbox.Event{Ch: 'w'}}, "w", true},
+		"arrow of the second":  {1, event{Event: termbox.Event{Key: termbox.KeyArrowLeft}}, "", false},
+		"second player":        {2, event{Event: termbox.Event{Key: termbox.KeyArrowLeft}}, "h", true},
+		"gg of the second":     {2, event{Event: termbox.Event{Key: termbox.KeyPgup}}, "gg", true},
+		"vim key of the first": {2, event{Event: termbox.Event{Ch: 'h'}}, "", false},
+		"own keyboard":         {2, event{Event: termbox.Event{Ch: 'w'}, player: 2}, "w", true},
+		"keyboard of another":  {1, event{Event: termbox.Event{Ch: 'w'}, player: 2}, "", false},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{number: tt.number}
+			keys, ok := p.keysOf(tt.ev)
+			if ok != tt.ok {
+				t.Errorf("expected %v but %v", tt.ok, ok)
+			}
+			if string(keys) != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, string(keys))
+			}
+		})
+	}
+}
+
+func TestPlayersState(t *testing.T) {
+	cases := map[string]struct {
+		states     []int
+		continuing bool
+		expected   int
+	}{
+		"alone":            {[]int{continuing}, true, lose},
+		"alone caught":     {[]int{lose}, false, lose},
+		"one caught":       {[]int{lose, continuing}, true, lose},
+		"both caught":      {[]int{lose, lose}, false, lose},
+		"win after caught": {[]int{lose, win}, false, win},
+		"quit":             {[]int{quit, continuing}, false, quit},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ps := players{}
+			for _, s := range tt.states {
+				ps = append(ps, &player{state: s})
+			}
+			if c := ps.continuing(); c != tt.continuing {
+				t.Errorf("expected %v but %v", tt.continuing, c)
+			}
+			if !tt.continuing {
+				if s := ps.state(); s != tt.expected {
+					t.Errorf("expected %d but %d", tt.expected, s)
+				}
+			}
+		})
+	}
+}
+
+// Test the two players eat the apples together, and the stage is won with the last apple.
+func TestTwoPlayerApples(t *testing.T) {
+	ps := newPlayers(keymap{}, coop)
+	s := playersTestInit(t, playerTestMapPath+"two_players.txt", ps)
+	p1, p2 := ps[0], ps[1]
+	if p1.targetScore != 8 || p2.targetScore != 8 {
+		t.Fatalf("expected %d but %d %d", 8, p1.targetScore, p2.targetScore)
+	}
+	for i := 0; i < 4; i++ {
+		p1.handle(event{Event: termbox.Event{Type: termbox.EventKey, Ch: 'h'}}, s)
+		p2.handle(event{Event: termbox.Event{Type: termbox.EventKey, Key: termbox.KeyArrowRight}}, s)
+	}
+	if p1.x == p2.x {
+		t.Error("expected the players to move apart")
+	}
+	if p1.score != 4 || p2.score != 4 {
+		t.Errorf("expected %d %d but %d %d", 4, 4, p1.score, p2.score)
+	}
+	if ps.continuing() || ps.state() != win {
+		t.Errorf("expected %d but %d", win, ps.state())
+	}
+}
+
+// Test the second player is shown without changing the cells of the stage.
+func TestFlushSecondPlayer(t *testing.T) {
+	ps := newPlayers(keymap{}, versus)
+	s := playersTestInit(t, playerTestMapPath+"two_players.txt", ps)
+	ps[1].handle(event{Event: termbox.Event{Type: termbox.EventKey, Key: termbox.KeyArrowRight}}, s)
+	if err := ps.flush(); err != nil {
+		t.Fatal(err)
+	}
+	cell := getCell(ps[1].x, ps[1].y)
+	if cell.Ch != chApple || cell.Fg != termbox.ColorGreen || cell.Bg != termbox.ColorBlack {
+		t.Errorf("unexpected cell %+v", cell)
+	}
+}
+
+// Test each enemy goes after the nearest player on the stage.
+func TestTarget(t *testing.T) {
+	p1 := &player{x: 2, y: 2, state: continuing}
+	p2 := &player{x: 10, y: 2, state: continuing}
+	e := newEnemyBuilder().defaultHunter().build()
+	e.setPosition(8, 3)
+	ps := players{p1, p2}
+	if target := ps.target(e); target != p2 {
+		t.Errorf("expected %+v but %+v", p2, target)
+	}
+	p2.state = lose
+	if target := ps.target(e); target != p1 {
+		t.Errorf("expected %+v but %+v", p1, target)
+	}
+}
+
+func TestResult(t *testing.T) {
+	cases := map[string]struct {
+		mode     int
+		points   []int
+		expected string
+	}{
+		"alone":         {onePlayer, []int{10}, ""},
+		"coop":          {coop, []int{10, 12}, "1P: 10  2P: 12"},
+		"1P wins":       {versus, []int{13, 12}, "1P wins! 13 - 12"},
+		"2P wins":       {versus, []int{10, 12}, "2P wins! 10 - 12"},
+		"draw":          {versus, []int{10, 10}, "Draw! 10 - 10"},
+		"three players": {versus, []int{10, 10, 11}, "3P wins! 10 - 10 - 11"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ps := players{}
+			for i, points := range tt.points {
+				ps = append(ps, &player{number: i + 1, score: points})
+			}
+			if r := ps.result(tt.mode); r != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, r)
+			}
+		})
+	}
+}
+
+func playersTestInit(t *testing.T, mapPath string, ps players) stage {
+	t.Helper()
+	if err := termbox.Init(); err != nil {
+		t.Error(err)
+	}
+	if err := termbox.Clear(termbox.ColorWhite, termbox.ColorBlack); err != nil {
+		t.Error(err)
+	}
+	t.Cleanup(func() {
+		termbox.Close()
+	})
+	s := stage{mapPath: mapPath, numPlayers: len(ps)}
+	f, err := static.ReadFile(s.mapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := createBuffer(bytes.NewReader(f))
+	w := createWindow(b)
+	if err = w.show(b); err != nil {
+		t.Fatal(err)
+	}
+	s.plot(b, ps)
+	for _, p := range ps {
+		p.state = continuing
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package main
+
+import "strconv"
+
+// Power-ups
+const (
+	// Enemies stop for a while
+	chFreeze = '*'
+	// The game runs at half speed for a while
+	chSlow = '~'
+	// The player survives the next hit of an enemy, and the enemy is sent home
+	chShield = '%'
+	// The player gets an extra life
+	chExtraLife = '&'
+)
+
+// Number of ticks the effects of power-ups last
+const (
+	freezeTime = 8
+	slowTime   = 15
+)
+
+// Apply the power-up the player has picked up.
+// The effects are counted down by the stage on each tick.
+func (p *player) pickUp(ch rune) {
+	switch ch {
+	case chFreeze:
+		// Enemies are frozen and slowed for all the players
+		for _, q := range p.teammates() {
+			q.frozen = freezeTime
+		}
+	case chSlow:
+		for _, q := range p.teammates() {
+			q.slowed = slowTime
+		}
+	case chShield:
+		p.shield = true
+	case chExtraLife:
+		p.extraLives++
+	}
+}
+
+func (p *player) countDownPowerUps() {
+	if p.frozen > 0 {
+		p.frozen--
+	}
+	if p.slowed > 0 {
+		p.slowed--
+	}
+}
+
+// Returns the effects of power-ups with their countdowns, e.g. " freeze: 5 shield".
+func (p *player) powerUpStatus() string {
+	status := ""
+	if p.frozen > 0 {
+		status += " freeze: " + strconv.Itoa(p.frozen)
+	}
+	if p.slowed > 0 {
+		status += " slow: " + strconv.Itoa(p.slowed)
+	}
+	if p.shield {
+		status += " shield"
+	}
+	if p.extraLives > 0 {
+		status += " life: +" + strconv.Itoa(p.extraLives)
+	}
+	return status
+}
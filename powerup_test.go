@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPickUp(t *testing.T) {
+	p := &player{
+		x:     1,
+		y:     1,
+		state: continuing,
+	}
+	s, offset, err := playerActionTestInit(t, playerTestMapPath+"power_up.txt", p)
+	if err != nil {
+		t.Error(err)
+	}
+	p.x += offset
+	p.inputNum = 5
+	p.moveCross(1, 0)
+	// Power-ups are not apples
+	if p.score != 1 {
+		t.Errorf("expected %d but %d", 1, p.score)
+	}
+	if p.frozen != freezeTime || p.slowed != slowTime || !p.shield || p.extraLives != 1 {
+		t.Errorf("expected %d %d %t %d but %d %d %t %d", freezeTime, slowTime, true, 1, p.frozen, p.slowed, p.shield, p.extraLives)
+	}
+	expected := " freeze: 8 slow: 15 shield life: +1"
+	if status := p.powerUpStatus(); status != expected {
+		t.Errorf("expected %q but %q", expected, status)
+	}
+	// A power-up is picked up only once
+	p.moveCross(-1, 0)
+	p.moveCross(1, 0)
+	if p.extraLives != 1 {
+		t.Errorf("expected %d but %d", 1, p.extraLives)
+	}
+	// Effects are counted down on each tick, and the shield lasts until it is hit
+	if err := s.control(players{p}); err != nil {
+		t.Error(err)
+	}
+	if p.frozen != freezeTime-1 || p.slowed != slowTime-1 || !p.shield || p.extraLives != 1 {
+		t.Errorf("expected %d %d %t %d but %d %d %t %d", freezeTime-1, slowTime-1, true, 1, p.frozen, p.slowed, p.shield, p.extraLives)
+	}
+}
+
+func TestPowerUpEffects(t *testing.T) {
+	cases := map[string]struct {
+		frozen bool
+		slowed bool
+		// Number of cells the enemy moves in 4 ticks
+		expectedMoves int
+		// Length of the ticks
+		expectedTick time.Duration
+	}{
+		"none":   {false, false, 4, defaultGameSpeed},
+		"freeze": {true, false, 0, defaultGameSpeed},
+		"slow":   {false, true, 4, defaultGameSpeed * 2},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter_with_obstacle.txt", newEnemyBuilder().defaultHunter())
+			if err != nil {
+				t.Error(err)
+			}
+			p.state = continuing
+			if tt.frozen {
+				p.frozen = freezeTime
+			}
+			if tt.slowed {
+				p.slowed = slowTime
+			}
+			e := s.enemies[0]
+			moves := 0
+			for i := 0; i < 4; i++ {
+				x, y := e.getPosition()
+				if err := s.control(players{p}); err != nil {
+					t.Error(err)
+				}
+				if nx, ny := e.getPosition(); nx != x || ny != y {
+					moves++
+				}
+			}
+			if moves != tt.expectedMoves {
+				t.Errorf("expected %d but %d", tt.expectedMoves, moves)
+			}
+			if tick := s.tickLength(players{p}); tick != tt.expectedTick {
+				t.Errorf("expected %v but %v", tt.expectedTick, tick)
+			}
+		})
+	}
+}
+
+func TestShield(t *testing.T) {
+	p := &player{
+		x:     6,
+		y:     1,
+		state: continuing,
+	}
+	s, offset, err := playerActionTestInit(t, playerTestMapPath+"power_up.txt", p)
+	if err != nil {
+		t.Error(err)
+	}
+	p.x += offset
+	p.shield = true
+	// The player walks into the enemy with the shield
+	p.inputNum = 3
+	p.moveCross(1, 0)
+	if p.state != continuing {
+		t.Errorf("expected %d but %d", continuing, p.state)
+	}
+	// The enemy is sent home and the shield is used up
+	if err := s.control(players{p}); err != nil {
+		t.Error(err)
+	}
+	if p.state != continuing || p.shield {
+		t.Errorf("expected %d %t but %d %t", continuing, false, p.state, p.shield)
+	}
+	if x, y := s.enemies[0].getPosition(); isCharEnemy(x, y) {
+		t.Error("expected the enemy to leave the board")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var progressValidationError = errors.New("Progress Validation Error")
+
+// progress is the progress of the game and the settings saved between games, e.g.
+//
+//	continue 3
+//	best 1 120
+//	best 2 95
+//	mode survival
+//	life 2
+type progress struct {
+	// Level to continue from (0 if there is nothing to continue)
+	level int
+	// Best scores of the stages by level
+	best     map[int]int
+	gameMode int
+	life     int
+}
+
+func newProgress() *progress {
+	return &progress{best: map[int]int{}, gameMode: normalGame, life: 2}
+}
+
+// Default location of the progress, next to pacvimrc.
+func defaultProgressPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "pacvim", "progress")
+}
+
+// Read the progress. A missing file is a new game.
+func loadProgress(filePath string) (*progress, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return newProgress(), nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parseProgress(f, filePath)
+}
+
+func parseProgress(r io.Reader, filePath string) (*progress, error) {
+	pr := newProgress()
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch {
+		case fields[0] == "continue" && len(fields) == 2:
+			pr.level, err = strconv.Atoi(fields[1])
+		case fields[0] == "best" && len(fields) == 3:
+			var level, score int
+			if level, err = strconv.Atoi(fields[1]); err == nil {
+				score, err = strconv.Atoi(fields[2])
+				pr.best[level] = score
+			}
+		case fields[0] == "mode" && len(fields) == 2:
+			pr.gameMode, err = parseGameMode(fields[1])
+		case fields[0] == "life" && len(fields) == 2:
+			pr.life, err = strconv.Atoi(fields[1])
+		default:
+			err = errors.New("unknown entry")
+		}
+		if err != nil {
+			err := errors.New(filePath + "; Invalid entry: " + scanner.Text() + " (line " + strconv.Itoa(lineNo) + ");")
+			return nil, fmt.Errorf("%w: %+v", progressValidationError, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// Write the progress. Nothing is saved without the location.
+func (pr *progress) save(filePath string) error {
+	if filePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	lines := []string{}
+	if pr.level > 0 {
+		lines = append(lines, "continue "+strconv.Itoa(pr.level))
+	}
+	levels := []int{}
+	for level := range pr.best {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	for _, level := range levels {
+		lines = append(lines, "best "+strconv.Itoa(level)+" "+strconv.Itoa(pr.best[level]))
+	}
+	lines = append(lines, "mode "+gameModeName(pr.gameMode), "life "+strconv.Itoa(pr.life))
+	return os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// Record the stage cleared with the score. The game continues from the next level.
+func (pr *progress) record(level, score, next int) {
+	if best, ok := pr.best[level]; !ok || score > best {
+		pr.best[level] = score
+	}
+	pr.level = next
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	cases := map[string]struct {
+		text     string
+		expected *progress
+		err      error
+	}{
+		"empty": {"", newProgress(), nil},
+		"full": {
+			"continue 3\nbest 1 120\nbest 2 95\n\nmode survival\nlife 4\n",
+			&progress{level: 3, best: map[int]int{1: 120, 2: 95}, gameMode: survival, life: 4},
+			nil,
+		},
+		"unknown entry": {"level 3\n", nil, progressValidationError},
+		"invalid level": {"continue three\n", nil, progressValidationError},
+		"invalid score": {"best 1\n", nil, progressValidationError},
+		"invalid mode":  {"mode hard\n", nil, progressValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			pr, err := parseProgress(strings.NewReader(tt.text), "progress")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v but %v", tt.err, err)
+			}
+			if tt.expected == nil {
+				return
+			}
+			if pr.level != tt.expected.level || pr.gameMode != tt.expected.gameMode || pr.life != tt.expected.life {
+				t.Errorf("expected %+v but %+v", tt.expected, pr)
+			}
+			if len(pr.best) != len(tt.expected.best) {
+				t.Errorf("expected %v but %v", tt.expected.best, pr.best)
+			}
+			for level, score := range tt.expected.best {
+				if pr.best[level] != score {
+					t.Errorf("expected %d but %d", score, pr.best[level])
+				}
+			}
+		})
+	}
+}
+
+func TestSaveProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pacvim", "progress")
+	pr, err := loadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr.record(1, 120, 2)
+	pr.record(1, 80, 2)
+	pr.record(2, 95, 3)
+	pr.gameMode = timeAttack
+	if err := pr.save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.level != 3 {
+		t.Errorf("expected %d but %d", 3, loaded.level)
+	}
+	// The lower score doesn't replace the best score
+	if loaded.best[1] != 120 || loaded.best[2] != 95 {
+		t.Errorf("unexpected best scores %v", loaded.best)
+	}
+	if loaded.gameMode != timeAttack || loaded.life != 2 {
+		t.Errorf("expected %d %d but %d %d", timeAttack, 2, loaded.gameMode, loaded.life)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Returns whether the player can type the key in the stage. q is always allowed.
+func (s stage) isAllowed(ch rune) bool {
+	if ch == 'q' {
+		return true
+	}
+	if s.allowedKeys != "" && !strings.ContainsRune(s.allowedKeys, ch) {
+		return false
+	}
+	return !strings.ContainsRune(s.forbiddenKeys, ch)
+}
+
+// Counts are allowed with the digits 1-9, and then 0 in a count is allowed too.
+func (s stage) allowsCounts() bool {
+	return s.isAllowed('1')
+}
+
+// The key not allowed is ignored with a warning, and costs the penalty of the stage.
+func (p *player) forbid(ch rune, s stage) {
+	p.initInput()
+	p.warning = "'" + string(ch) + "' is not allowed"
+	if s.keyPenalty > 0 {
+		p.bonus -= s.keyPenalty
+		p.warning += " (-" + strconv.Itoa(s.keyPenalty) + ")"
+	}
+}
+
+// Returns the rule of the keys of the stage shown under the stage, e.g. "Only: w b e counts".
+func (s stage) keyRule() string {
+	if s.allowedKeys != "" {
+		return "Only: " + describeKeys(s.allowedKeys)
+	}
+	if s.forbiddenKeys != "" {
+		return "No: " + describeKeys(s.forbiddenKeys)
+	}
+	return ""
+}
+
+func describeKeys(keys string) string {
+	names := []string{}
+	counts := false
+	for _, r := range keys {
+		switch {
+		case r >= '1' && r <= '9':
+			counts = true
+		case r == 'g':
+			names = append(names, "gg")
+		default:
+			names = append(names, string(r))
+		}
+	}
+	if counts {
+		names = append(names, "counts")
+	}
+	return strings.Join(names, " ")
+}
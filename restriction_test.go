@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+)
+
+// Test the keys not allowed in the stage are ignored.
+func TestAllowedKeys(t *testing.T) {
+	const initX, initY = 7, 5
+	cases := map[string]struct {
+		allowedKeys   string
+		forbiddenKeys string
+		inputs        string
+		expectedX     int
+		expectedY     int
+	}{
+		"all keys":           {"", "", "2lj", initX + 2, initY + 1},
+		"allowed":            {"hjkl", "", "lj", initX + 1, initY + 1},
+		"not allowed":        {"jk", "", "lhj", initX, initY + 1},
+		"counts not allowed": {"hjkl", "", "2lj", initX + 1, initY + 1},
+		"counts allowed":     {"hjkl123456789", "", "2lj", initX + 2, initY + 1},
+		"zero in a count":    {"jl123456789", "", "10lj", initX + 4, initY + 1},
+		"repeat not allowed": {"hjkl", "", "l.", initX + 1, initY},
+		"only jumps":         {"gG", "", "wG", 1, 9},
+		"forbidden":          {"", "hl", "lhj", initX, initY + 1},
+		"counts forbidden":   {"", "123456789", "2lj", initX + 1, initY + 1},
+		"quit always":        {"hjkl", "q", "lq", initX + 1, initY},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x: initX,
+				y: initY,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			s.allowedKeys = tt.allowedKeys
+			s.forbiddenKeys = tt.forbiddenKeys
+			p.x += offset
+			tt.expectedX += offset
+			for _, r := range tt.inputs {
+				p.input(r, s)
+			}
+			if !(p.x == tt.expectedX && p.y == tt.expectedY) {
+				t.Errorf("expected %d %d but %d %d", tt.expectedX, tt.expectedY, p.x, p.y)
+			}
+		})
+	}
+}
+
+// Test a key not allowed shows the warning until the next key and costs the penalty.
+func TestForbid(t *testing.T) {
+	cases := map[string]struct {
+		keyPenalty int
+		inputs     string
+		warning    string
+		bonus      int
+	}{
+		"warning":             {0, "h", "'h' is not allowed", 0},
+		"penalty":             {5, "h", "'h' is not allowed (-5)", -5},
+		"penalty each time":   {5, "hjh", "'h' is not allowed (-5)", -15},
+		"cleared by next key": {5, "hl", "", -5},
+		"count is cleared":    {5, "3h", "'h' is not allowed (-5)", -5},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x: 7,
+				y: 5,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			s.forbiddenKeys = "hjk"
+			s.keyPenalty = tt.keyPenalty
+			for _, r := range tt.inputs {
+				p.input(r, s)
+			}
+			if p.warning != tt.warning {
+				t.Errorf("expected %q but %q", tt.warning, p.warning)
+			}
+			if p.bonus != tt.bonus {
+				t.Errorf("expected %d but %d", tt.bonus, p.bonus)
+			}
+			if p.inputNum != 0 {
+				t.Errorf("expected %d but %d", 0, p.inputNum)
+			}
+		})
+	}
+}
+
+func TestKeyRule(t *testing.T) {
+	cases := map[string]struct {
+		allowedKeys   string
+		forbiddenKeys string
+		expected      string
+	}{
+		"no rule":   {"", "", ""},
+		"only":      {"wbe123456789", "", "Only: w b e counts"},
+		"only gg":   {"gG", "", "Only: gg G"},
+		"forbidden": {"", "hjkl", "No: h j k l"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s := stage{allowedKeys: tt.allowedKeys, forbiddenKeys: tt.forbiddenKeys}
+			if rule := s.keyRule(); rule != tt.expected {
+				t.Errorf("expected %q but %q", tt.expected, rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import termbox "github.com/nsf/termbox-go"
+
+// iScreen is where the game is drawn and the keys come from.
+// The cells of the screen hold the state of the stage, so the game runs on any screen.
+type iScreen interface {
+	setCell(x, y int, ch rune, fg, bg termbox.Attribute)
+	cellBuffer() []termbox.Cell
+	size() (int, int)
+	clear(fg, bg termbox.Attribute) error
+	flush() error
+	setCursor(x, y int)
+	hideCursor()
+	pollEvent() event
+}
+
+// event is a key typed on the screen. The player is set when the key comes from the player's own keyboard.
+type event struct {
+	termbox.Event
+	player int
+}
+
+// The screen of the game. The game is played on the terminal unless it is served over the network or SSH.
+var screen iScreen = terminal{}
+
+// grid is the cells of a screen kept in memory, e.g. on the server.
+// The cells changed since the last flush are sent to the clients or drawn on the flush.
+type grid struct {
+	width  int
+	height int
+	cells  []termbox.Cell
+	sent   []termbox.Cell
+}
+
+// Returns the cleared cells. The screen starts with them.
+func newGrid(width, height int) grid {
+	g := grid{
+		width:  width,
+		height: height,
+		cells:  make([]termbox.Cell, width*height),
+		sent:   make([]termbox.Cell, width*height),
+	}
+	g.clear(termbox.ColorWhite, termbox.ColorBlack)
+	copy(g.sent, g.cells)
+	return g
+}
+
+func (g *grid) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if x < 0 || x >= g.width || y < 0 || y >= g.height {
+		return
+	}
+	g.cells[y*g.width+x] = termbox.Cell{Ch: ch, Fg: fg, Bg: bg}
+}
+func (g *grid) cellBuffer() []termbox.Cell {
+	return g.cells
+}
+func (g *grid) size() (int, int) {
+	return g.width, g.height
+}
+func (g *grid) clear(fg, bg termbox.Attribute) error {
+	for i := range g.cells {
+		g.cells[i] = termbox.Cell{Ch: chSpace, Fg: fg, Bg: bg}
+	}
+	return nil
+}
+
+// Calls fn with each cell changed since the last call.
+func (g *grid) changes(fn func(x, y int, c termbox.Cell)) {
+	for i, c := range g.cells {
+		if c != g.sent[i] {
+			fn(i%g.width, i/g.width, c)
+			g.sent[i] = c
+		}
+	}
+}
+
+// terminal is the terminal PacVim runs in.
+type terminal struct{}
+
+func (terminal) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+func (terminal) cellBuffer() []termbox.Cell {
+	return termbox.CellBuffer()
+}
+func (terminal) size() (int, int) {
+	return termbox.Size()
+}
+func (terminal) clear(fg, bg termbox.Attribute) error {
+	return termbox.Clear(fg, bg)
+}
+func (terminal) flush() error {
+	return termbox.Flush()
+}
+func (terminal) setCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+func (terminal) hideCursor() {
+	termbox.HideCursor()
+}
+func (terminal) pollEvent() event {
+	return event{Event: termbox.PollEvent()}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// Limit of the steps of a script call, so that a script can't freeze the game.
+const maxScriptSteps = 100000
+
+var scriptValidationError = errors.New("Script Validation Error")
+
+// Functions of a script that drive the enemies of the stage, e.g. def hunter(x, y): ...
+var scriptFunctions = []string{"hunter", "ghost", "patrol"}
+
+// script evaluates the cells with a function of a Starlark script.
+// Like other strategies, the lower value is the better cell.
+type script struct {
+	filePath string
+	fn       *starlark.Function
+	self     *enemy
+	stage    *stage
+	err      error
+}
+
+// The script of a stage is the file next to the map, e.g. files/stage/map01.star.
+// When the directory of scripts is given, the script in it is used instead.
+func scriptPath(mapPath, dir string) string {
+	name := strings.TrimSuffix(mapPath, filepath.Ext(mapPath)) + ".star"
+	if dir != "" {
+		return filepath.Join(dir, filepath.Base(name))
+	}
+	return name
+}
+
+// Load the functions of the script of the stage that drive its enemies.
+// A stage without the script keeps its strategies. The builders of the stage are kept as they are,
+// so that the script is read again each time the stage is played.
+func (s *stage) loadScript() error {
+	filePath := scriptPath(s.mapPath, s.scriptDir)
+	functions, err := s.readScript()
+	if err != nil {
+		return err
+	}
+	s.scripts = map[string]strategy{}
+	for name, fn := range functions {
+		s.scripts[name] = &script{filePath: filePath, fn: fn}
+	}
+	return nil
+}
+
+// Returns the enemy of the builder, driven by the function of the script if the stage has it.
+func (s stage) buildEnemy(name string, eb iEnemyBuilder) iEnemy {
+	if sc, ok := s.scripts[name]; ok {
+		eb = eb.copy().strategize(sc)
+	}
+	return eb.build()
+}
+
+// Returns the functions for enemies of the script of the stage (nil if the stage has no script).
+func (s stage) readScript() (map[string]*starlark.Function, error) {
+	filePath := scriptPath(s.mapPath, s.scriptDir)
+	var src []byte
+	var err error
+	if s.scriptDir != "" {
+		src, err = os.ReadFile(filePath)
+	} else {
+		src, err = static.ReadFile(filePath)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseScript(src, filePath)
+}
+
+// Run the top level of the script and returns the functions for enemies.
+func parseScript(src []byte, filePath string) (map[string]*starlark.Function, error) {
+	thread := newScriptThread(nil, nil, nil)
+	globals, err := starlark.ExecFile(thread, filePath, src, scriptBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %+v", scriptValidationError, errors.New(filePath+"; "+err.Error()+";"))
+	}
+	functions := map[string]*starlark.Function{}
+	for _, name := range scriptFunctions {
+		v, ok := globals[name]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(*starlark.Function)
+		if !ok || fn.NumParams() != 2 {
+			err := errors.New(filePath + "; Define " + name + " as a function of x and y;")
+			return nil, fmt.Errorf("%w: %+v", scriptValidationError, err)
+		}
+		functions[name] = fn
+	}
+	if len(functions) == 0 {
+		err := errors.New(filePath + "; Define hunter, ghost or patrol as a function of x and y;")
+		return nil, fmt.Errorf("%w: %+v", scriptValidationError, err)
+	}
+	return functions, nil
+}
+
+func newScriptThread(e *enemy, p *player, st *stage) *starlark.Thread {
+	thread := &starlark.Thread{
+		// Scripts can't load other files
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not allowed: " + module)
+		},
+		Print: func(_ *starlark.Thread, _ string) {},
+	}
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+	thread.SetLocal("enemy", e)
+	thread.SetLocal("player", p)
+	thread.SetLocal("stage", st)
+	return thread
+}
+
+func (s *script) bind(e *enemy, st *stage) strategy {
+	return &script{filePath: s.filePath, fn: s.fn, self: e, stage: st}
+}
+
+func (s *script) eval(p *player, x, y int) float64 {
+	if s.err != nil {
+		return 1000
+	}
+	v, err := starlark.Call(newScriptThread(s.self, p, s.stage), s.fn, starlark.Tuple{starlark.MakeInt(x), starlark.MakeInt(y)}, nil)
+	if err == nil {
+		if f, ok := starlark.AsFloat(v); ok {
+			return f
+		}
+		err = errors.New(s.fn.Name() + " returned " + v.Type() + ", not a number")
+	}
+	// The stage stops with the error
+	s.err = fmt.Errorf("%w: %+v", scriptValidationError, errors.New(s.filePath+"; "+err.Error()+";"))
+	return 1000
+}
+
+// Returns the error of the script while the game is running.
+func (s *script) failed() error {
+	return s.err
+}
+
+// Functions the scripts can call. Scripts can only see the board through them.
+var scriptBuiltins = starlark.StringDict{
+	// player() returns the position of the player
+	"player": starlark.NewBuiltin("player", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+			return nil, err
+		}
+		p, ok := thread.Local("player").(*player)
+		if !ok || p == nil {
+			return nil, errors.New("player() can't be called outside of an enemy function")
+		}
+		return point{p.x, p.y}.tuple(), nil
+	}),
+	// direction() returns the direction the player is moving
+	"direction": starlark.NewBuiltin("direction", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+			return nil, err
+		}
+		p, ok := thread.Local("player").(*player)
+		if !ok || p == nil {
+			return nil, errors.New("direction() can't be called outside of an enemy function")
+		}
+		return point{p.dirX, p.dirY}.tuple(), nil
+	}),
+	// position() returns the position of the enemy
+	"position": starlark.NewBuiltin("position", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+			return nil, err
+		}
+		e, ok := thread.Local("enemy").(*enemy)
+		if !ok || e == nil {
+			return nil, errors.New("position() can't be called outside of an enemy function")
+		}
+		return point{e.x, e.y}.tuple(), nil
+	}),
+	// walkable(x, y) returns whether the enemy can move to the cell
+	"walkable": starlark.NewBuiltin("walkable", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x, y int
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &x, "y", &y); err != nil {
+			return nil, err
+		}
+		e, ok := thread.Local("enemy").(*enemy)
+		st, _ := thread.Local("stage").(*stage)
+		if !ok || e == nil || st == nil {
+			return nil, errors.New("walkable() can't be called outside of an enemy function")
+		}
+		// The cells off the stage are not on the screen
+		if x < 0 || y < 0 || x >= st.width || y >= st.height {
+			return starlark.False, nil
+		}
+		return starlark.Bool(e.canMove(x, y)), nil
+	}),
+	// rand(n) returns a random integer between 0 and n-1
+	"rand": starlark.NewBuiltin("rand", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var n int
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "n", &n); err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, errors.New("rand: n must be positive")
+		}
+		return starlark.MakeInt(random(0, n-1)), nil
+	}),
+	// distance(x1, y1, x2, y2) returns the distance between two cells
+	"distance": starlark.NewBuiltin("distance", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x1, y1, x2, y2 int
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "x1", &x1, "y1", &y1, "x2", &x2, "y2", &y2); err != nil {
+			return nil, err
+		}
+		return starlark.Float(math.Sqrt(math.Pow(float64(y2-y1), 2) + math.Pow(float64(x2-x1), 2))), nil
+	}),
+}
+
+func (pt point) tuple() starlark.Tuple {
+	return starlark.Tuple{starlark.MakeInt(pt.x), starlark.MakeInt(pt.y)}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+const assaultScript = `
+def hunter(x, y):
+    px, py = player()
+    return distance(x, y, px, py)
+`
+
+func TestParseScript(t *testing.T) {
+	cases := map[string]struct {
+		src       string
+		functions int
+		err       bool
+	}{
+		"hunter":             {assaultScript, 1, false},
+		"hunter and ghost":   {assaultScript + "\ndef ghost(x, y):\n    return rand(10)\n", 2, false},
+		"helper functions":   {"def near(x, y):\n    return 0\n\ndef patrol(x, y):\n    return near(x, y)\n", 1, false},
+		"syntax error":       {"def hunter(x, y)\n    return 0\n", 0, true},
+		"no enemy functions": {"def chase(x, y):\n    return 0\n", 0, true},
+		"not a function":     {"hunter = 1\n", 0, true},
+		"wrong parameters":   {"def hunter(x):\n    return 0\n", 0, true},
+		"load other files":   {"load('os.star', 'exec')\n" + assaultScript, 0, true},
+		"player at top":      {"p = player()\n" + assaultScript, 0, true},
+		"endless top level":  {"n = 0\nfor i in range(10000000):\n    n += i\n" + assaultScript, 0, true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			functions, err := parseScript([]byte(tt.src), "test.star")
+			if tt.err {
+				if !errors.Is(err, scriptValidationError) {
+					t.Errorf("expected %v but %v", scriptValidationError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+			if len(functions) != tt.functions {
+				t.Errorf("expected %d but %d", tt.functions, len(functions))
+			}
+		})
+	}
+}
+
+// Test a script drives the enemy like the strategy written in Go.
+func TestScriptThink(t *testing.T) {
+	functions, err := parseScript([]byte(assaultScript), "test.star")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter_with_obstacle.txt", newEnemyBuilder().defaultHunter().strategize(&script{fn: functions["hunter"]}))
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0]
+	expected := newEnemyBuilder().defaultHunter().build()
+	expected.setPosition(e.getPosition())
+	for i := 0; i < 3; i++ {
+		x, y := e.think(p)
+		ex, ey := expected.think(p)
+		if x != ex || y != ey {
+			t.Errorf("expected %d %d but %d %d", ex, ey, x, y)
+		}
+		e.accelerate(defaultGameSpeed, 1)
+		e.move(x, y)
+		expected.setPosition(e.getPosition())
+	}
+}
+
+func TestScriptWalkable(t *testing.T) {
+	cases := map[string]struct {
+		x        int
+		y        int
+		expected bool
+	}{
+		"space":             {1, 1, true},
+		"boundary":          {0, 1, false},
+		"negative":          {-100, -100, false},
+		"beyond the width":  {1000, 1, false},
+		"beyond the height": {1, 1000, false},
+	}
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0].(*enemy)
+	offset := getOffset(s.height)
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			x := tt.x
+			if x >= 0 {
+				x += offset
+			}
+			v, err := starlark.Call(newScriptThread(e, p, &s), scriptBuiltins["walkable"], starlark.Tuple{starlark.MakeInt(x), starlark.MakeInt(tt.y)}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != starlark.Bool(tt.expected) {
+				t.Errorf("expected %v but %v", tt.expected, v)
+			}
+		})
+	}
+}
+
+// Test the stage stops with the error of a script.
+func TestScriptFailed(t *testing.T) {
+	cases := map[string]struct {
+		src string
+	}{
+		"endless loop":   {"def hunter(x, y):\n    n = 0\n    for i in range(10000000):\n        n += i\n    return n\n"},
+		"not a number":   {"def hunter(x, y):\n    return 'up'\n"},
+		"runtime error":  {"def hunter(x, y):\n    return 1 // 0\n"},
+		"wrong argument": {"def hunter(x, y):\n    return rand(0)\n"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			functions, err := parseScript([]byte(tt.src), "test.star")
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter().strategize(&script{fn: functions["hunter"]}))
+			if err != nil {
+				t.Error(err)
+			}
+			p.state = continuing
+			if err := s.control(players{p}); !errors.Is(err, scriptValidationError) {
+				t.Errorf("expected %v but %v", scriptValidationError, err)
+			}
+		})
+	}
+}
+
+// Test the script in the directory given by -scripts drives the enemies of the stage.
+func TestLoadScript(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "map01.star"), []byte(assaultScript), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := stage{
+		mapPath:       "files/stage/map01.txt",
+		hunterBuilder: newEnemyBuilder().defaultHunter(),
+		scriptDir:     dir,
+	}
+	// The stage is played again after the player is caught
+	for i := 0; i < 2; i++ {
+		if err := s.loadScript(); err != nil {
+			t.Error(err)
+		}
+		if _, ok := s.buildEnemy("hunter", s.hunterBuilder).(*enemy).strategy.(*script); !ok {
+			t.Error("expected the hunter to be driven by the script")
+		}
+		// The builder of the stage is kept as it is
+		if _, ok := s.hunterBuilder.build().(*enemy).strategy.(*assault); !ok {
+			t.Error("expected the builder to keep its strategy")
+		}
+	}
+	// The sample script in the repository drives the hunter of level 1
+	s.scriptDir = "files/scripts"
+	if err := s.loadScript(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.scripts["hunter"]; !ok {
+		t.Error("expected the sample script to drive the hunter")
+	}
+	// A stage without the script keeps its strategies
+	s.mapPath = "files/stage/map02.txt"
+	if err := s.loadScript(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := s.buildEnemy("hunter", s.hunterBuilder).(*enemy).strategy.(*assault); !ok {
+		t.Error("expected the hunter to keep its strategy")
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Size of the screen of the game served over the network
+const (
+	serverWidth  = 80
+	serverHeight = 30
+	// Each player has a line of the score under the stage
+	maxServerPlayers = 4
+)
+
+// A client that doesn't read the screen for this long leaves the game.
+const clientWriteTimeout = 5 * time.Second
+
+var protocolValidationError = errors.New("Protocol Validation Error")
+
+// The key typed when all the clients have left, so that the game ends.
+var quitEvent = event{Event: termbox.Event{Type: termbox.EventKey, Ch: 'q'}}
+
+// netScreen is the screen of the game served over the network.
+// The cells are kept in memory, and the changes are sent to the clients when the screen is flushed:
+//
+//	cell x y ch fg bg
+//	cursor x y
+//	flush
+//
+// The clients send the keys typed by the players, e.g. "key 0 106" for j.
+type netScreen struct {
+	grid
+	mu      sync.Mutex
+	clients map[int]*client
+	events  chan event
+	// Closed when all the clients have left
+	gone chan struct{}
+	// Closed when the game is over, so that nobody waits for the keys to be read
+	done    chan struct{}
+	players players
+	log     io.Writer
+}
+
+type client struct {
+	conn    net.Conn
+	w       *bufio.Writer
+	cursor  point
+	visible bool
+}
+
+func newNetScreen(width, height int, log io.Writer) *netScreen {
+	return &netScreen{
+		grid:    newGrid(width, height),
+		clients: map[int]*client{},
+		events:  make(chan event, 64),
+		gone:    make(chan struct{}),
+		done:    make(chan struct{}),
+		log:     log,
+	}
+}
+
+// Send the changed cells and the cursor of each player to the clients.
+func (ns *netScreen) flush() error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	var diff strings.Builder
+	ns.changes(func(x, y int, c termbox.Cell) {
+		fmt.Fprintf(&diff, "cell %d %d %d %d %d\n", x, y, c.Ch, c.Fg, c.Bg)
+	})
+	for n, c := range ns.clients {
+		cursor := "hide\n"
+		if c.visible {
+			cursor = "cursor " + strconv.Itoa(c.cursor.x) + " " + strconv.Itoa(c.cursor.y) + "\n"
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		_, err := c.w.WriteString(diff.String() + cursor + "flush\n")
+		if err == nil {
+			err = c.w.Flush()
+		}
+		// The game goes on without the client that can't be reached
+		if err != nil {
+			ns.drop(n)
+			ns.retire(n)
+		}
+	}
+	return nil
+}
+
+// The cursors are of the players. See setPlayerCursor.
+func (ns *netScreen) setCursor(x, y int) {}
+
+func (ns *netScreen) hideCursor() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	for _, c := range ns.clients {
+		c.visible = false
+	}
+}
+
+// Returns the next key typed by a player, or q when all the players have left.
+// The players who have left are taken out here, so that only the game changes the players.
+func (ns *netScreen) pollEvent() event {
+	var ev event
+	select {
+	case ev = <-ns.events:
+	case <-ns.gone:
+		// The keys typed before leaving come first
+		select {
+		case ev = <-ns.events:
+		default:
+			return quitEvent
+		}
+	}
+	if ev.Type == termbox.EventNone {
+		boardMu.Lock()
+		defer boardMu.Unlock()
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		ns.retire(ev.player)
+	}
+	return ev
+}
+
+func (ns *netScreen) setPlayerCursor(number, x, y int, visible bool) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if c, ok := ns.clients[number]; ok {
+		c.cursor, c.visible = point{x, y}, visible
+	}
+}
+
+// Add the client of the player, and start receiving the keys of it.
+func (ns *netScreen) join(number int, conn net.Conn) {
+	ns.mu.Lock()
+	ns.clients[number] = &client{conn: conn, w: bufio.NewWriter(conn)}
+	ns.mu.Unlock()
+	fmt.Fprintf(ns.log, "%dP joined from %s\n", number, conn.RemoteAddr())
+	go ns.receive(number, conn)
+}
+
+func (ns *netScreen) receive(number int, conn net.Conn) {
+	defer ns.leave(number)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		ev, err := parseKey(scanner.Text(), conn.RemoteAddr().String())
+		if err != nil {
+			fmt.Fprintln(ns.log, err)
+			return
+		}
+		// q leaves the game, but doesn't end it for the others
+		if ev.Ch == 'q' {
+			return
+		}
+		ev.player = number
+		select {
+		case ns.events <- ev:
+		case <-ns.done:
+			return
+		}
+	}
+}
+
+// Parse the key sent by the client, e.g. "key 0 106".
+func parseKey(text, addr string) (event, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 3 && fields[0] == "key" {
+		key, err1 := strconv.ParseUint(fields[1], 10, 16)
+		ch, err2 := strconv.ParseInt(fields[2], 10, 32)
+		if err1 == nil && err2 == nil {
+			return event{Event: termbox.Event{Type: termbox.EventKey, Key: termbox.Key(key), Ch: rune(ch)}}, nil
+		}
+	}
+	err := errors.New(addr + "; Invalid message: " + text + ";")
+	return event{}, fmt.Errorf("%w: %+v", protocolValidationError, err)
+}
+
+// The player of the client is out of the game. The game takes the player out on the next event.
+func (ns *netScreen) leave(number int) {
+	select {
+	case ns.events <- event{Event: termbox.Event{Type: termbox.EventNone}, player: number}:
+	case <-ns.done:
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.drop(number)
+}
+
+func (ns *netScreen) drop(number int) {
+	c, ok := ns.clients[number]
+	if !ok {
+		return
+	}
+	c.conn.Close()
+	delete(ns.clients, number)
+	fmt.Fprintf(ns.log, "%dP left\n", number)
+	if len(ns.clients) == 0 {
+		close(ns.gone)
+	}
+}
+
+func (ns *netScreen) retire(number int) {
+	for _, p := range ns.players {
+		if p.number == number && p.state == continuing {
+			p.state = lose
+		}
+	}
+}
+
+// Bind the players of the stage to the clients. The players whose clients have left are out.
+func (ns *netScreen) bind(ps players) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.players = ps
+	for _, p := range ps {
+		if _, ok := ns.clients[p.number]; !ok {
+			ns.retire(p.number)
+		}
+	}
+}
+
+// Say goodbye to the clients.
+func (ns *netScreen) close() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	close(ns.done)
+	for n, c := range ns.clients {
+		c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		c.w.WriteString("bye\n")
+		c.w.Flush()
+		ns.drop(n)
+	}
+}
+
+// Serve the game to the players joining with "pacvim join".
+func runServe(args []string, w io.Writer, errW io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	addr := fs.String("addr", ":7777", "Address to listen on.")
+	n := fs.Int("players", 2, "Number of players to wait for.")
+	level := fs.Int("level", 1, "Level at the start of the game.")
+	life := fs.Int("life", 2, "Remaining lives shared by the players.")
+	ruleName := fs.String("rule", "versus", "coop or versus.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rule, err := parseTwoPlayerMode(*ruleName)
+	if err != nil {
+		return err
+	}
+	if *n < 1 || *n > maxServerPlayers {
+		return errors.New("Choose 1 to " + strconv.Itoa(maxServerPlayers) + " players")
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Waiting for %d players on %s\n", *n, ln.Addr())
+	ns := newNetScreen(serverWidth, serverHeight, w)
+	for i := 1; i <= *n; i++ {
+		conn, err := ln.Accept()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ns.join(i, conn)
+	}
+	ln.Close()
+
+	screen = ns
+	defer func() {
+		ns.close()
+		screen = terminal{}
+	}()
+	return serveGame(ns, splitStages(initStages(), level), *n, *life, rule)
+}
+
+// Play the stages with the players on the server.
+func serveGame(ns *netScreen, stages []stage, n, life, rule int) error {
+	if err := switchScene(sceneStart); err != nil {
+		return err
+	}
+	i := 0
+game:
+	for i < len(stages) && life >= 0 {
+		ps := newTeam(n, keymap{})
+		stages[i].numPlayers = n
+		if err := stages[i].init(ps, life); err != nil {
+			return err
+		}
+
+		standBy(ps)
+		ns.bind(ps)
+
+		if err := stages[i].start(ps); err != nil {
+			return err
+		}
+		for _, p := range ps {
+			life += p.extraLives
+		}
+
+		switch ps.state() {
+		case win:
+			if err := switchScene(sceneYouwin, ps.result(rule)); err != nil {
+				return err
+			}
+			i++
+		case lose:
+			if err := switchScene(sceneYoulose, ps.result(rule)); err != nil {
+				return err
+			}
+			life--
+		case quit:
+			break game
+		}
+	}
+	return switchScene(sceneGoodbye)
+}
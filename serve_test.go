@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+func TestParseKey(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		key  termbox.Key
+		ch   rune
+		err  error
+	}{
+		"char":        {"key 0 106", 0, 'j', nil},
+		"special key": {"key 65517 0", termbox.KeyArrowUp, 0, nil},
+		"no key":      {"key 106", 0, 0, protocolValidationError},
+		"not a key":   {"cell 0 0 106 0 0", 0, 0, protocolValidationError},
+		"not numbers": {"key a j", 0, 0, protocolValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ev, err := parseKey(tt.text, "client")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v but %v", tt.err, err)
+			}
+			if ev.Key != tt.key || ev.Ch != tt.ch {
+				t.Errorf("expected %d %q but %d %q", tt.key, tt.ch, ev.Key, ev.Ch)
+			}
+		})
+	}
+}
+
+func TestReceiveScreen(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		err  error
+	}{
+		"screen":         {"cell 2 1 111 8 1\ncursor 2 1\nflush\nhide\nbye\n", nil},
+		"closed":         {"cell 2 1 111 8 1\n", nil},
+		"unknown":        {"cell 2 1 111 8 1\nbeep\n", protocolValidationError},
+		"missing number": {"cell 2 1 111 8\n", protocolValidationError},
+		"empty line":     {"\n", protocolValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			dst := newNetScreen(4, 3, io.Discard)
+			err := receiveScreen(strings.NewReader(tt.text), dst, "server")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v but %v", tt.err, err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if cell := dst.cellBuffer()[1*4+2]; cell.Ch != chApple || cell.Fg != termbox.ColorWhite {
+				t.Errorf("unexpected cell %+v", cell)
+			}
+		})
+	}
+}
+
+// Test the keys and the screen go between the server and the client on localhost.
+func TestNetScreen(t *testing.T) {
+	ns, conn := netScreenTestInit(t)
+	fmt.Fprintln(conn, "key 0 106")
+	ev := ns.pollEvent()
+	if ev.Ch != 'j' || ev.player != 1 {
+		t.Errorf("expected %q %d but %q %d", 'j', 1, ev.Ch, ev.player)
+	}
+
+	ns.setCell(2, 1, chApple, termbox.ColorWhite, termbox.ColorBlack)
+	ns.setPlayerCursor(1, 2, 1, true)
+	if err := ns.flush(); err != nil {
+		t.Fatal(err)
+	}
+	ns.close()
+	dst := newNetScreen(ns.width, ns.height, io.Discard)
+	if err := receiveScreen(conn, dst, "server"); err != nil {
+		t.Fatal(err)
+	}
+	for i, cell := range dst.cellBuffer() {
+		if cell != ns.cellBuffer()[i] {
+			t.Errorf("expected %+v but %+v", ns.cellBuffer()[i], cell)
+		}
+	}
+}
+
+// Test the player of the client that leaves is out, and the game ends when all the players leave.
+func TestLeave(t *testing.T) {
+	ns, conn := netScreenTestInit(t)
+	ps := newTeam(2, keymap{})
+	for _, p := range ps {
+		p.state = continuing
+	}
+	ns.bind(ps)
+	// The second player hasn't joined
+	if ps[1].state != lose {
+		t.Errorf("expected %d but %d", lose, ps[1].state)
+	}
+	fmt.Fprintln(conn, "key 0 113")
+	// The game takes the player out when it reads the leave
+	if ev := ns.pollEvent(); ev.Type != termbox.EventNone || ev.player != 1 {
+		t.Errorf("expected %d %d but %d %d", termbox.EventNone, 1, ev.Type, ev.player)
+	}
+	if ps[0].state != lose {
+		t.Errorf("expected %d but %d", lose, ps[0].state)
+	}
+	if ev := ns.pollEvent(); ev != quitEvent {
+		t.Errorf("expected %+v but %+v", quitEvent, ev)
+	}
+}
+
+// Test the client doesn't wait for the keys to be read after the game is over.
+func TestLeaveAfterClose(t *testing.T) {
+	ns, _ := netScreenTestInit(t)
+	for len(ns.events) < cap(ns.events) {
+		ns.events <- event{}
+	}
+	ns.close()
+	left := make(chan struct{})
+	go func() {
+		ns.leave(1)
+		close(left)
+	}()
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Error("expected the client to leave")
+	}
+}
+
+// Test a game is served and ends when the player leaves.
+func TestServe(t *testing.T) {
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- runServe([]string{"-addr", "127.0.0.1:0", "-players", "1"}, w, io.Discard)
+	}()
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(io.Discard, r)
+	fields := strings.Fields(line)
+	conn, err := net.Dial("tcp", fields[len(fields)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	received := make(chan error, 1)
+	dst := newNetScreen(serverWidth, serverHeight, io.Discard)
+	go func() {
+		received <- receiveScreen(conn, dst, "server")
+	}()
+	// Wait for the stage, and leave
+	time.Sleep(time.Second)
+	fmt.Fprintf(conn, "key %d 0\n", termbox.KeyEnter)
+	fmt.Fprintln(conn, "key 0 113")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the game to end")
+	}
+	if _, ok := screen.(terminal); !ok {
+		t.Error("expected the screen to be the terminal again")
+	}
+}
+
+func netScreenTestInit(t *testing.T) (*netScreen, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := newNetScreen(serverWidth, serverHeight, io.Discard)
+	ns.join(1, server)
+	return ns, conn
+}
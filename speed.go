@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conditions of the steps of a speed curve
+const (
+	// Percentage of the apples eaten
+	byApples int = iota
+	// Seconds since the stage started
+	byTime
+)
+
+// Length of a tick when the stage doesn't set gameSpeed
+const defaultGameSpeed = time.Second
+
+var speedCurveValidationError = errors.New("Speed Curve Validation Error")
+
+// speedStep multiplies the speed of enemies once the threshold is reached.
+// A later step of the same condition replaces the earlier ones.
+type speedStep struct {
+	condition  int
+	threshold  int
+	multiplier float64
+	// Characters of the enemies to accelerate (all enemies if empty)
+	enemies []rune
+}
+
+// The speed curve of a stage is read from the file next to the map, e.g. files/stage/map01.speed.
+func speedCurvePath(mapPath string) string {
+	return strings.TrimSuffix(mapPath, filepath.Ext(mapPath)) + ".speed"
+}
+
+// Read the speed curve of the stage. A stage without the file has a constant speed.
+func loadSpeedCurve(filePath string) ([]speedStep, error) {
+	f, err := static.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parseSpeedCurve(f, filePath)
+}
+
+// Each line is written as '{apples|time} {threshold} {multiplier} [enemies]', e.g.
+//
+//	apples 50% 1.2 H
+//	time 60s 1.5
+func parseSpeedCurve(r io.Reader, filePath string) ([]speedStep, error) {
+	steps := []speedStep{}
+	last := map[int]int{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		// Skip blank lines and comments
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 && len(fields) != 4 {
+			return nil, speedCurveError(filePath, lineNo, "Write a step as '{apples|time} {threshold} {multiplier} [enemies]'")
+		}
+		var step speedStep
+		var unit string
+		switch fields[0] {
+		case "apples":
+			step.condition, unit = byApples, "%"
+		case "time":
+			step.condition, unit = byTime, "s"
+		default:
+			return nil, speedCurveError(filePath, lineNo, "Unknown condition: "+fields[0])
+		}
+		threshold, err := strconv.Atoi(strings.TrimSuffix(fields[1], unit))
+		if err != nil || threshold < 0 || (step.condition == byApples && threshold > 100) {
+			return nil, speedCurveError(filePath, lineNo, "Invalid threshold: "+fields[1])
+		}
+		if prev, ok := last[step.condition]; ok && threshold <= prev {
+			return nil, speedCurveError(filePath, lineNo, "Thresholds must be in ascending order: "+fields[1])
+		}
+		last[step.condition] = threshold
+		step.threshold = threshold
+		step.multiplier, err = strconv.ParseFloat(fields[2], 64)
+		if err != nil || step.multiplier < 0 {
+			return nil, speedCurveError(filePath, lineNo, "Invalid multiplier: "+fields[2])
+		}
+		if len(fields) == 4 {
+			step.enemies = []rune(fields[3])
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+func speedCurveError(filePath string, lineNo int, msg string) error {
+	err := errors.New(filePath + "; " + msg + " (line " + strconv.Itoa(lineNo) + ");")
+	return fmt.Errorf("%w: %+v", speedCurveValidationError, err)
+}
+
+func (s stage) getGameSpeed() time.Duration {
+	if s.gameSpeed == 0 {
+		return defaultGameSpeed
+	}
+	return s.gameSpeed
+}
+
+// Returns how long the current tick lasts. The slow power-up halves the game speed, so the tick lasts twice as long.
+func (s stage) tickLength(ps players) time.Duration {
+	for _, p := range ps {
+		if p.slowed > 0 {
+			return s.getGameSpeed() * 2
+		}
+	}
+	return s.getGameSpeed()
+}
+
+// Returns how much faster the enemy moves at the current tick.
+// The multipliers of the apple curve and the time curve are multiplied together.
+func (s stage) speedMultiplier(e iEnemy, p *player) float64 {
+	eaten := 0
+	if p.targetScore > 0 {
+		eaten = p.applesEaten() * 100 / p.targetScore
+	}
+	elapsed := int((time.Duration(s.tick) * s.getGameSpeed()).Seconds())
+	char, _ := e.getDisplayFormat()
+	multipliers := map[int]float64{}
+	for _, step := range s.speedCurve {
+		if len(step.enemies) > 0 && !strings.ContainsRune(string(step.enemies), char) {
+			continue
+		}
+		if (step.condition == byApples && eaten >= step.threshold) || (step.condition == byTime && elapsed >= step.threshold) {
+			multipliers[step.condition] = step.multiplier
+		}
+	}
+	m := 1.0
+	for _, v := range multipliers {
+		m *= v
+	}
+	return m
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSpeedCurve(t *testing.T) {
+	cases := map[string]struct {
+		input    string
+		expected []speedStep
+		err      bool
+	}{
+		"apples and time": {
+			input: "# Elroy\napples 50% 1.2 H\n\napples 80% 1.5 H\ntime 60s 2",
+			expected: []speedStep{
+				{condition: byApples, threshold: 50, multiplier: 1.2, enemies: []rune{'H'}},
+				{condition: byApples, threshold: 80, multiplier: 1.5, enemies: []rune{'H'}},
+				{condition: byTime, threshold: 60, multiplier: 2},
+			},
+		},
+		"empty":                {input: "", expected: []speedStep{}},
+		"unknown condition":    {input: "score 10 1.2", err: true},
+		"missing multiplier":   {input: "apples 50%", err: true},
+		"over 100%":            {input: "apples 120% 1.2", err: true},
+		"descending threshold": {input: "time 60s 1.2\ntime 30s 1.5", err: true},
+		"negative multiplier":  {input: "time 60s -1", err: true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseSpeedCurve(strings.NewReader(tt.input), "test.speed")
+			if tt.err {
+				if !errors.Is(err, speedCurveValidationError) {
+					t.Errorf("expected %v but %v", speedCurveValidationError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("expected %d but %d", len(tt.expected), len(actual))
+			}
+			for i := range actual {
+				a, e := actual[i], tt.expected[i]
+				if a.condition != e.condition || a.threshold != e.threshold || a.multiplier != e.multiplier || string(a.enemies) != string(e.enemies) {
+					t.Errorf("expected %v but %v", e, a)
+				}
+			}
+		})
+	}
+}
+
+func TestSpeedMultiplier(t *testing.T) {
+	curve := []speedStep{
+		{condition: byApples, threshold: 50, multiplier: 1.5, enemies: []rune{chHunter}},
+		{condition: byApples, threshold: 80, multiplier: 2, enemies: []rune{chHunter}},
+		{condition: byTime, threshold: 10, multiplier: 1.5},
+	}
+	cases := map[string]struct {
+		char     rune
+		score    int
+		tick     int
+		expected float64
+	}{
+		"start":                    {chHunter, 0, 0, 1},
+		"half of the apples":       {chHunter, 5, 0, 1.5},
+		"most of the apples":       {chHunter, 9, 0, 2},
+		"ghost ignores the apples": {chGhost, 9, 0, 1},
+		"time":                     {chGhost, 0, 10, 1.5},
+		"apples and time":          {chHunter, 5, 10, 2.25},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s := stage{speedCurve: curve, tick: tt.tick}
+			p := &player{score: tt.score, targetScore: 10}
+			e := &enemy{char: tt.char}
+			if actual := s.speedMultiplier(e, p); actual != tt.expected {
+				t.Errorf("expected %f but %f", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// Test enemies move at their own speed regardless of the length of a tick.
+func TestEnemySpeed(t *testing.T) {
+	cases := map[string]struct {
+		speed     float64
+		gameSpeed time.Duration
+		// Number of cells moved at each tick
+		expected []int
+	}{
+		"slower than a tick":   {0.4, time.Second, []int{0, 0, 1, 0, 1}},
+		"one cell a tick":      {1, time.Second, []int{1, 1}},
+		"faster than a tick":   {2, time.Second, []int{2}},
+		"short tick":           {1, 500 * time.Millisecond, []int{0, 1, 0, 1}},
+		"stays still":          {0, time.Second, []int{0, 0, 0}},
+		"default tick of test": {0.5, 0, []int{0, 1}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p, s, err := enemyActionTestInit(t, enemyTestMapPath+"frightened.txt", newEnemyBuilder().defaultHunter().speed(tt.speed))
+			if err != nil {
+				t.Error(err)
+			}
+			s.gameSpeed = tt.gameSpeed
+			e := s.enemies[0]
+			p.state = continuing
+			for i, expected := range tt.expected {
+				x, y := e.getPosition()
+				if err := s.control(players{p}); err != nil {
+					t.Error(err)
+				}
+				nx, ny := e.getPosition()
+				if actual := abs(nx-x) + abs(ny-y); actual != expected {
+					t.Errorf("tick %d: expected %d but %d", i+1, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+// Test the enemies of the levels of the original game still move on the same ticks,
+// i.e. a hunter on every tick and a ghost on every second tick.
+func TestOriginalPace(t *testing.T) {
+	cases := map[string]struct {
+		level int
+		ghost bool
+		// The enemy moves once in this number of ticks
+		every int
+	}{
+		"level 1 hunter": {1, false, 1},
+		"level 2 hunter": {2, false, 1},
+		"level 2 ghost":  {2, true, 2},
+		"level 3 hunter": {3, false, 1},
+		"level 3 ghost":  {3, true, 2},
+		"level 4 hunter": {4, false, 1},
+		"level 5 hunter": {5, false, 1},
+	}
+	stages := initStages()
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s := stages[tt.level-1]
+			eb := s.hunterBuilder
+			if tt.ghost {
+				eb = s.ghostBuilder
+			}
+			e := eb.copy().build().(*enemy)
+			for tick := 1; tick <= 1000; tick++ {
+				e.accelerate(s.getGameSpeed(), 1)
+				moved := e.isReady()
+				if moved {
+					e.progress -= stepCost
+				}
+				if expected := tick%tt.every == 0; moved != expected {
+					t.Errorf("tick %d: expected %t but %t", tick, expected, moved)
+					return
+				}
+			}
+		})
+	}
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
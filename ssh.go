@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Smallest terminal a stage fits in with the lines under it
+const (
+	minSessionWidth  = 60
+	minSessionHeight = 28
+	// Entries shown on the leaderboard
+	leaderboardSize = 10
+)
+
+// The host key is generated in this file on the first start, so that the players see the same key each time.
+const defaultHostKey = "host_key"
+
+var sshValidationError = errors.New("SSH Validation Error")
+
+// leaderboard is the best results of the players across the SSH sessions.
+type leaderboard struct {
+	mu      sync.Mutex
+	entries []sessionResult
+}
+
+// sessionResult is the result of a game in an SSH session, reported by the session with a line like
+//
+//	result 230 4
+type sessionResult struct {
+	name   string
+	points int
+	level  int
+}
+
+// Add the result, and returns the rank of it (0 if it is not on the leaderboard).
+func (lb *leaderboard) add(r sessionResult) int {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.entries = append(lb.entries, r)
+	sort.SliceStable(lb.entries, func(i, j int) bool {
+		if lb.entries[i].points != lb.entries[j].points {
+			return lb.entries[i].points > lb.entries[j].points
+		}
+		return lb.entries[i].level > lb.entries[j].level
+	})
+	if len(lb.entries) > leaderboardSize {
+		lb.entries = lb.entries[:leaderboardSize]
+	}
+	for i := range lb.entries {
+		if lb.entries[i] == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Returns the leaderboard as text, e.g.
+//
+//	Leaderboard (the names are not verified)
+//	 1. alice      230 (level 4)
+//	 2. bob        120 (level 2)
+//
+// The names are the SSH user names, which anyone who can connect may choose.
+func (lb *leaderboard) String() string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lines := []string{"Leaderboard (the names are not verified)"}
+	for i, r := range lb.entries {
+		lines = append(lines, fmt.Sprintf("%2d. %-10s %4d (level %d)", i+1, r.name, r.points, r.level))
+	}
+	// The terminal of the session is raw, so the lines end with CRLF
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// Parse the result reported by the session, e.g. "result 230 4".
+func parseSessionResult(text string) (sessionResult, bool) {
+	var r sessionResult
+	fields := strings.Fields(text)
+	if len(fields) != 3 || fields[0] != "result" {
+		return r, false
+	}
+	var err1, err2 error
+	r.points, err1 = strconv.Atoi(fields[1])
+	r.level, err2 = strconv.Atoi(fields[2])
+	return r, err1 == nil && err2 == nil
+}
+
+// sshServer hosts a game for each SSH session.
+// The state of a game is on its screen, so each game runs in its own process of "pacvim session".
+type sshServer struct {
+	board leaderboard
+	// Returns the command of the game of a session
+	command func(args ...string) *exec.Cmd
+	level   int
+	life    int
+	log     io.Writer
+	// Each session runs a process, so the sessions at the same time are limited (no limit if 0)
+	mu          sync.Mutex
+	sessions    int
+	maxSessions int
+}
+
+// Count the session in, and returns false when the server is full.
+func (srv *sshServer) enter() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.maxSessions > 0 && srv.sessions >= srv.maxSessions {
+		return false
+	}
+	srv.sessions++
+	return true
+}
+
+func (srv *sshServer) exit() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.sessions--
+}
+
+func (srv *sshServer) handle(s ssh.Session) {
+	if !srv.enter() {
+		fmt.Fprint(s, "The server is full. Try again later.\r\n")
+		s.Exit(1)
+		return
+	}
+	defer srv.exit()
+	pty, winCh, ok := s.Pty()
+	if !ok {
+		fmt.Fprint(s, "PacVim needs a terminal. Connect with ssh -t.\r\n")
+		s.Exit(1)
+		return
+	}
+	// The size of the screen is fixed when the game starts
+	go func() {
+		for range winCh {
+		}
+	}()
+	if pty.Window.Width < minSessionWidth || pty.Window.Height < minSessionHeight {
+		fmt.Fprintf(s, "Make the terminal at least %dx%d.\r\n", minSessionWidth, minSessionHeight)
+		s.Exit(1)
+		return
+	}
+	fmt.Fprintf(srv.log, "%s joined from %s\n", s.User(), s.RemoteAddr())
+
+	cmd := srv.command("session",
+		"-width", strconv.Itoa(pty.Window.Width), "-height", strconv.Itoa(pty.Window.Height),
+		"-level", strconv.Itoa(srv.level), "-life", strconv.Itoa(srv.life))
+	cmd.Stdout = s
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		// The keys are copied until the session ends, not waited for
+		go io.Copy(stdin, s)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(srv.log, err)
+		s.Exit(1)
+		return
+	}
+	result, reported := sessionResult{}, false
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if r, ok := parseSessionResult(scanner.Text()); ok {
+			result, reported = r, true
+		} else {
+			fmt.Fprintf(srv.log, "%s: %s\n", s.User(), scanner.Text())
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		fmt.Fprintf(srv.log, "%s: %v\n", s.User(), err)
+	}
+
+	// Only the players who cleared a stage are on the leaderboard
+	if reported && result.level > 0 {
+		result.name = s.User()
+		if rank := srv.board.add(result); rank > 0 {
+			fmt.Fprintf(s, "You are #%d with %d points!\r\n", rank, result.points)
+		}
+	}
+	fmt.Fprint(s, srv.board.String())
+	fmt.Fprintf(srv.log, "%s left\n", s.User())
+	s.Exit(0)
+}
+
+// Host the game over SSH. The players connect with "ssh -p 2222 name@host".
+func runSSH(args []string, w io.Writer, errW io.Writer) error {
+	fs := flag.NewFlagSet("ssh", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	addr := fs.String("addr", ":2222", "Address to listen on.")
+	hostKey := fs.String("host-key", defaultHostKey, "Path of the host key, generated if it doesn't exist.")
+	level := fs.Int("level", 1, "Level at the start of the game.")
+	life := fs.Int("life", 2, "Remaining lives.")
+	maxSessions := fs.Int("max-sessions", 20, "Number of sessions at the same time. (0 for no limit)")
+	password := fs.String("password", "", "Password the players log in with.")
+	authorizedKeys := fs.String("authorized-keys", "", "Path of the authorized_keys file of the players.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	options, err := authOptions(*password, *authorizedKeys)
+	if err != nil {
+		return err
+	}
+	if err := generateHostKey(*hostKey); err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	srv := &sshServer{
+		command: func(args ...string) *exec.Cmd {
+			return exec.Command(exe, args...)
+		},
+		level:       *level,
+		life:        *life,
+		log:         w,
+		maxSessions: *maxSessions,
+	}
+	server := &ssh.Server{Addr: *addr, Handler: srv.handle}
+	for _, option := range append(options, ssh.HostKeyFile(*hostKey)) {
+		if err := server.SetOption(option); err != nil {
+			return err
+		}
+	}
+	if len(options) == 0 {
+		fmt.Fprintln(w, "Anyone can connect. Set -password or -authorized-keys to let in only your players.")
+	}
+	fmt.Fprintln(w, "Listening on", *addr)
+	return server.ListenAndServe()
+}
+
+// Returns the options of the server that let in only the players with the password or a key of the authorized_keys file.
+// Anyone can connect when neither is given.
+func authOptions(password, authorizedKeys string) ([]ssh.Option, error) {
+	options := []ssh.Option{}
+	if password != "" {
+		options = append(options, ssh.PasswordAuth(func(_ ssh.Context, p string) bool {
+			return subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		}))
+	}
+	if authorizedKeys != "" {
+		keys, err := readAuthorizedKeys(authorizedKeys)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, ssh.PublicKeyAuth(func(_ ssh.Context, key ssh.PublicKey) bool {
+			for _, k := range keys {
+				if ssh.KeysEqual(k, key) {
+					return true
+				}
+			}
+			return false
+		}))
+	}
+	return options, nil
+}
+
+// Returns the keys of the authorized_keys file, one key on each line.
+func readAuthorizedKeys(filePath string) ([]ssh.PublicKey, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	keys := []ssh.PublicKey{}
+	for i, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			err := errors.New(filePath + "; Invalid key (line " + strconv.Itoa(i+1) + ");")
+			return nil, fmt.Errorf("%w: %+v", sshValidationError, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		err := errors.New(filePath + "; Add the keys of the players;")
+		return nil, fmt.Errorf("%w: %+v", sshValidationError, err)
+	}
+	return keys, nil
+}
+
+// Generate the host key in the file unless it exists.
+func generateHostKey(filePath string) error {
+	if _, err := os.Stat(filePath); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// Play the game of an SSH session on the terminal of the session, and report the result.
+func runSession(args []string, in io.Reader, out io.Writer, errW io.Writer) error {
+	fs := flag.NewFlagSet("session", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	width := fs.Int("width", serverWidth, "Width of the terminal.")
+	height := fs.Int("height", serverHeight, "Height of the terminal.")
+	level := fs.Int("level", 1, "Level at the start of the game.")
+	life := fs.Int("life", 2, "Remaining lives.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	stages := initStages()
+	if err := validateFiles(stages); err != nil {
+		return err
+	}
+
+	as := newANSIScreen(*width, *height, in, out)
+	screen = as
+	defer func() {
+		as.leave()
+		screen = terminal{}
+	}()
+	result, err := playSession(splitStages(stages, level), *life)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(errW, "result %d %d\n", result.points, result.level)
+	return err
+}
+
+// Play the stages alone. The result is the points of the cleared stages and the last level cleared.
+func playSession(stages []stage, life int) (sessionResult, error) {
+	result := sessionResult{}
+	if err := switchScene(sceneStart); err != nil {
+		return result, err
+	}
+	i := 0
+game:
+	for i < len(stages) && life >= 0 {
+		ps := newPlayers(keymap{}, onePlayer)
+		if err := stages[i].init(ps, life); err != nil {
+			return result, err
+		}
+
+		standBy(ps)
+
+		if err := stages[i].start(ps); err != nil {
+			return result, err
+		}
+		p := ps[0]
+		life += p.extraLives
+
+		switch p.state {
+		case win:
+			result.points += p.points()
+			result.level = stages[i].level
+			if err := switchScene(sceneYouwin); err != nil {
+				return result, err
+			}
+			i++
+		case lose:
+			if err := switchScene(sceneYoulose); err != nil {
+				return result, err
+			}
+			life--
+		case quit:
+			break game
+		}
+	}
+	if err := switchScene(sceneGoodbye, "Points: "+strconv.Itoa(result.points)); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestLeaderboard(t *testing.T) {
+	cases := map[string]struct {
+		results  []sessionResult
+		expected []int
+	}{
+		"by points":      {[]sessionResult{{"a", 10, 1}, {"b", 30, 2}, {"c", 20, 2}}, []int{1, 1, 2}},
+		"by level":       {[]sessionResult{{"a", 10, 1}, {"b", 10, 2}}, []int{1, 1}},
+		"out of the top": {[]sessionResult{{"a", 10, 1}, {"b", 10, 1}, {"c", 10, 1}, {"d", 10, 1}, {"e", 10, 1}, {"f", 10, 1}, {"g", 10, 1}, {"h", 10, 1}, {"i", 10, 1}, {"j", 10, 1}, {"k", 0, 0}}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 0}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			lb := &leaderboard{}
+			for i, r := range tt.results {
+				if rank := lb.add(r); rank != tt.expected[i] {
+					t.Errorf("expected %d but %d", tt.expected[i], rank)
+				}
+			}
+			if len(lb.entries) > leaderboardSize {
+				t.Errorf("expected %d but %d", leaderboardSize, len(lb.entries))
+			}
+		})
+	}
+}
+
+func TestParseSessionResult(t *testing.T) {
+	cases := map[string]struct {
+		text     string
+		expected sessionResult
+		ok       bool
+	}{
+		"result":      {"result 230 4", sessionResult{points: 230, level: 4}, true},
+		"log":         {"stage 1 failed", sessionResult{}, false},
+		"not numbers": {"result a 4", sessionResult{}, false},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			r, ok := parseSessionResult(tt.text)
+			if ok != tt.ok {
+				t.Fatalf("expected %v but %v", tt.ok, ok)
+			}
+			if ok && r != tt.expected {
+				t.Errorf("expected %+v but %+v", tt.expected, r)
+			}
+		})
+	}
+}
+
+// Test a session plays the game on its PTY and sees the leaderboard, with a fake game on localhost.
+func TestSSH(t *testing.T) {
+	srv := &sshServer{
+		command: func(args ...string) *exec.Cmd {
+			if args[0] != "session" {
+				t.Errorf("expected %s but %s", "session", args[0])
+			}
+			return exec.Command("sh", "-c", "read key; echo game $key; echo result 120 3 >&2")
+		},
+		log: io.Discard,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &ssh.Server{Handler: srv.handle}
+	go server.Serve(ln)
+	defer server.Close()
+
+	cases := map[string]struct {
+		user     string
+		width    int
+		expected []string
+	}{
+		"first":      {"alice", 80, []string{"game j", "You are #1 with 120 points!", " 1. alice       120 (level 3)"}},
+		"second":     {"bob", 80, []string{" 1. alice", " 2. bob"}},
+		"too narrow": {"carol", 40, []string{"Make the terminal at least 60x28."}},
+		"full":       {"dave", 80, []string{"The server is full."}},
+	}
+	for _, name := range []string{"first", "second", "too narrow", "full"} {
+		tt := cases[name]
+		t.Run(name, func(t *testing.T) {
+			if name == "full" {
+				srv.maxSessions = 1
+				srv.enter()
+				defer srv.exit()
+			}
+			out := sshTestRun(t, ln.Addr().String(), tt.user, tt.width)
+			for _, e := range tt.expected {
+				if !strings.Contains(out, e) {
+					t.Errorf("expected %q in %q", e, out)
+				}
+			}
+		})
+	}
+}
+
+// Test only the players with the password or a key of the authorized_keys file can connect.
+func TestAuthOptions(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := gossh.NewSignerFromKey(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizedKeys := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(authorizedKeys, append([]byte("# players\n"), gossh.MarshalAuthorizedKey(sshPub)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		password       string
+		authorizedKeys string
+		auth           gossh.AuthMethod
+		expected       bool
+	}{
+		"no auth":           {"", "", gossh.Password(""), true},
+		"right password":    {"secret", "", gossh.Password("secret"), true},
+		"wrong password":    {"secret", "", gossh.Password("guess"), false},
+		"authorized key":    {"", authorizedKeys, gossh.PublicKeys(signer), true},
+		"unauthorized key":  {"", authorizedKeys, gossh.PublicKeys(otherSigner), false},
+		"password with key": {"secret", authorizedKeys, gossh.Password("secret"), true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			options, err := authOptions(tt.password, tt.authorizedKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := &ssh.Server{Handler: func(s ssh.Session) {}}
+			for _, option := range options {
+				if err := server.SetOption(option); err != nil {
+					t.Fatal(err)
+				}
+			}
+			go server.Serve(ln)
+			defer server.Close()
+			client, err := gossh.Dial("tcp", ln.Addr().String(), &gossh.ClientConfig{
+				User:            "alice",
+				Auth:            []gossh.AuthMethod{tt.auth},
+				HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+			})
+			if err == nil {
+				client.Close()
+			}
+			if actual := err == nil; actual != tt.expected {
+				t.Errorf("expected %t but %t (%v)", tt.expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestReadAuthorizedKeys(t *testing.T) {
+	cases := map[string]struct {
+		src string
+		err error
+	}{
+		"invalid key": {"ssh-ed25519 AAAA\n", sshValidationError},
+		"no keys":     {"# players\n\n", sshValidationError},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "authorized_keys")
+			if err := os.WriteFile(filePath, []byte(tt.src), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readAuthorizedKeys(filePath); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v but %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// Test the host key is generated once and kept for the next start.
+func TestGenerateHostKey(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "host_key")
+	if err := generateHostKey(filePath); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generateHostKey(filePath); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("expected the host key to be kept")
+	}
+	server := &ssh.Server{}
+	if err := server.SetOption(ssh.HostKeyFile(filePath)); err != nil {
+		t.Error(err)
+	}
+}
+
+func sshTestRun(t *testing.T, addr, user string, width int) string {
+	t.Helper()
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            user,
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	if err := session.RequestPty("xterm", 30, width, gossh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	out := &strings.Builder{}
+	session.Stdout = out
+	session.Stdin = strings.NewReader("j\n")
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	session.Wait()
+	return out.String()
+}
@@ -10,23 +10,63 @@ import (
 )
 
 type stage struct {
-	level         int
-	mapPath       string
+	level   int
+	mapPath string
+	// Map built in memory, e.g. by the generator. If empty, the map is read from mapPath.
+	mapData       []byte
 	hunterBuilder iEnemyBuilder
 	ghostBuilder  iEnemyBuilder
+	patrolBuilder iEnemyBuilder
 	enemies       []iEnemy
-	gameSpeed     time.Duration
-	width         int
-	height        int
+	// Waypoints of patrols in the map (without line numbers)
+	waypoints []point
+	// Whether the digits 1-9 in the map are the waypoints of patrols instead. Otherwise they are text.
+	digitWaypoints bool
+	route          []point
+	// Length of a tick. Enemies move at their own speed in cells per second.
+	gameSpeed time.Duration
+	// Multipliers of the speed of enemies as the stage goes on
+	speedCurve []speedStep
+	// Directory of the scripts of enemies used instead of the embedded ones
+	scriptDir string
+	// Strategies of the script of the stage by the function name, e.g. hunter
+	scripts map[string]strategy
+	// Keys the player can type in the stage (all keys if empty). Digits allow counts.
+	allowedKeys string
+	// Keys the player can't type in the stage
+	forbiddenKeys string
+	// Points lost each time the player types a key not allowed
+	keyPenalty int
+	// Lesson of the tutorial taught by the stage
+	lesson *lesson
+	// normalGame, timeAttack or survival
+	gameMode int
+	// Number of players sharing the stage. Each player has a line of the score.
+	numPlayers int
+	// Number of moves enemies are frightened after the player eats a power pellet
+	frightenedTime int
+	// Enemies switch between scatter and chase in this order (the last phase lasts forever)
+	phases []phase
+	tick   int
+	width  int
+	height int
 }
 
+type phase struct {
+	mode  int
+	ticks int
+}
+
+const defaultFrightenedTime = 10
+
 func initStages() []stage {
 	return []stage{
 		{
-			level:         1,
-			mapPath:       "files/stage/map01.txt",
-			hunterBuilder: newEnemyBuilder().defaultHunter(),
-			gameSpeed:     1250 * time.Millisecond,
+			level:          1,
+			mapPath:        "files/stage/map01.txt",
+			hunterBuilder:  newEnemyBuilder().defaultHunter().speed(0.8),
+			gameSpeed:      1250 * time.Millisecond,
+			frightenedTime: 12,
 		},
 		{
 			level:         2,
@@ -39,26 +79,68 @@ func initStages() []stage {
 			level:         3,
 			mapPath:       "files/stage/map03.txt",
 			hunterBuilder: newEnemyBuilder().defaultHunter(),
-			ghostBuilder:  newEnemyBuilder().defaultGhost(),
+			ghostBuilder:  newEnemyBuilder().defaultGhost().strategize(&flanker{partnerChar: chHunter}),
 			gameSpeed:     1000 * time.Millisecond,
+			phases: []phase{
+				{mode: scatter, ticks: 7},
+				{mode: chase, ticks: 20},
+				{mode: scatter, ticks: 5},
+				{mode: chase},
+			},
 		},
 		{
 			level:         4,
 			mapPath:       "files/stage/map04.txt",
-			hunterBuilder: newEnemyBuilder().defaultHunter(),
+			hunterBuilder: newEnemyBuilder().defaultHunter().speed(4.0 / 3).strategize(&ambusher{}),
 			gameSpeed:     750 * time.Millisecond,
 		},
 		{
 			level:         5,
 			mapPath:       "files/stage/map05.txt",
-			hunterBuilder: newEnemyBuilder().defaultHunter().strategize(&tricky{}),
+			hunterBuilder: newEnemyBuilder().defaultHunter().speed(4.0 / 3).strategize(&tricky{}),
 			gameSpeed:     750 * time.Millisecond,
 		},
+		{
+			level:          6,
+			mapPath:        "files/stage/map06.txt",
+			patrolBuilder:  newEnemyBuilder().defaultPatrol().speed(2),
+			digitWaypoints: true,
+			gameSpeed:      500 * time.Millisecond,
+		},
+		{
+			level:         7,
+			mapPath:       "files/stage/map07.txt",
+			hunterBuilder: newEnemyBuilder().defaultHunter().speed(4.0 / 3).strategize(&vimMotion{}),
+			gameSpeed:     750 * time.Millisecond,
+		},
+		{
+			level:         8,
+			mapPath:       "files/stage/map08.txt",
+			hunterBuilder: newEnemyBuilder().defaultHunter().strategize(&pathfinding{}),
+			ghostBuilder:  newEnemyBuilder().defaultGhost().strategize(&lineOfSight{}),
+			gameSpeed:     750 * time.Millisecond,
+		},
+		{
+			level:         9,
+			mapPath:       "files/stage/map09.txt",
+			hunterBuilder: newEnemyBuilder().defaultHunter().speed(0.8),
+			gameSpeed:     1000 * time.Millisecond,
+			forbiddenKeys: "hjkl",
+			keyPenalty:    5,
+		},
+		{
+			level:        10,
+			mapPath:      "files/stage/map10.txt",
+			ghostBuilder: newEnemyBuilder().defaultGhost(),
+			gameSpeed:    1000 * time.Millisecond,
+			allowedKeys:  "wbegG123456789",
+			keyPenalty:   5,
+		},
 	}
 }
 
-func (s *stage) init(p *player, life int) error {
-	f, err := static.ReadFile(s.mapPath)
+func (s *stage) init(ps players, life int) error {
+	f, err := s.readMap()
 	if err != nil {
 		return err
 	}
@@ -69,55 +151,125 @@ func (s *stage) init(p *player, life int) error {
 		return err
 	}
 
-	s.plot(b, p)
-	p.plotScore(*s)
+	if s.speedCurve, err = loadSpeedCurve(speedCurvePath(s.mapPath)); err != nil {
+		return err
+	}
+	if err = s.loadScript(); err != nil {
+		return err
+	}
+
+	s.plot(b, ps)
+	for _, p := range ps {
+		p.gameMode = s.gameMode
+		p.plotScore(*s)
+	}
 	s.plotSubInfo(life)
 
-	if err = termbox.Flush(); err != nil {
+	if err = ps.flush(); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *stage) plot(b *buffer, p *player) {
+func (s stage) readMap() ([]byte, error) {
+	if len(s.mapData) > 0 {
+		return s.mapData, nil
+	}
+	return static.ReadFile(s.mapPath)
+}
+
+func (s *stage) plot(b *buffer, ps players) {
 	s.enemies = nil
+	s.route = nil
 	s.width = len(b.lines[0].text) + b.offset
 	s.height = len(b.lines)
+	waypoints := map[rune]point{}
+	teleporters := []point{}
+	doors := []point{}
 	for y := 0; y < s.height; y++ {
 		for x := b.offset; x < s.width; x++ {
-			if isCharApple(x, y) {
-				p.targetScore++
+			if s.digitWaypoints && isCharWaypoint(x, y) {
+				waypoints[getCell(x, y).Ch] = point{x, y}
+				screen.setCell(x, y, chSpace, termbox.ColorWhite, termbox.ColorBlack)
+			} else if isCharApple(x, y) {
+				for _, p := range ps {
+					p.targetScore++
+				}
 			} else if isCharPlayer(x, y) {
-				p.x, p.y = x, y
-				termbox.SetCell(p.x, p.y, chSpace, termbox.ColorWhite, termbox.ColorBlack)
-				termbox.SetCursor(p.x, p.y)
+				// The players start from the same cell
+				for _, p := range ps {
+					p.x, p.y = x, y
+					p.trail = newTrail(x, y)
+				}
+				screen.setCell(x, y, chSpace, termbox.ColorWhite, termbox.ColorBlack)
+				screen.setCursor(x, y)
 			} else if isCharBoundary(x, y) {
-				termbox.SetCell(x, y, chBoundary, termbox.ColorYellow, termbox.ColorBlack)
+				screen.setCell(x, y, chBoundary, termbox.ColorYellow, termbox.ColorBlack)
 			} else if isCharObstacle(x, y) {
-				var r rune
+				// A wall of a cell keeps its character
+				r := getCell(x, y).Ch
 				if isCharObstacle(x-1, y) || isCharObstacle(x+1, y) {
 					r = chObstacle1
 				} else if isCharObstacle(x, y-1) || isCharObstacle(x, y+1) {
 					r = chObstacle2
 				}
-				termbox.SetCell(x, y, r, termbox.ColorYellow, termbox.ColorBlack)
+				screen.setCell(x, y, r, termbox.ColorYellow, termbox.ColorBlack)
 			} else if isCharPoison(x, y) {
-				termbox.SetCell(x, y, chPoison, termbox.ColorMagenta, termbox.ColorBlack)
+				screen.setCell(x, y, chPoison, termbox.ColorMagenta, termbox.ColorBlack)
+			} else if isCharPellet(x, y) {
+				screen.setCell(x, y, chPellet, termbox.ColorWhite, termbox.ColorBlack)
+			} else if isCharPowerUp(x, y) {
+				screen.setCell(x, y, getCell(x, y).Ch, termbox.ColorWhite, termbox.ColorBlack)
+			} else if isCharTeleporter(x, y) {
+				teleporters = append(teleporters, point{x, y})
+				screen.setCell(x, y, chTeleporter, termbox.ColorCyan, termbox.ColorBlack)
+			} else if isCharOneWay(x, y) {
+				screen.setCell(x, y, getCell(x, y).Ch, termbox.ColorYellow, termbox.ColorBlack)
+			} else if isCharDoor(x, y) {
+				doors = append(doors, point{x, y})
+				screen.setCell(x, y, chDoor, termbox.ColorYellow, termbox.ColorBlack)
+			} else if isCharKey(x, y) {
+				screen.setCell(x, y, chKey, termbox.ColorWhite, termbox.ColorBlack)
 			} else if isCharHunter(x, y) {
-				h := s.hunterBuilder.build()
+				h := s.buildEnemy("hunter", s.hunterBuilder)
 				h.setPosition(x, y)
+				h.setHome(x, y)
 				char, color := h.getDisplayFormat()
-				termbox.SetCell(x, y, char, color, color)
+				screen.setCell(x, y, char, color, color)
 				s.enemies = append(s.enemies, h)
 			} else if isCharGhost(x, y) {
-				g := s.ghostBuilder.build()
+				g := s.buildEnemy("ghost", s.ghostBuilder)
 				g.setPosition(x, y)
+				g.setHome(x, y)
 				char, color := g.getDisplayFormat()
-				termbox.SetCell(x, y, char, color, color)
+				screen.setCell(x, y, char, color, color)
 				s.enemies = append(s.enemies, g)
+			} else if isCharPatrol(x, y) {
+				r := s.buildEnemy("patrol", s.patrolBuilder)
+				r.setPosition(x, y)
+				r.setHome(x, y)
+				char, color := r.getDisplayFormat()
+				screen.setCell(x, y, char, color, color)
+				s.enemies = append(s.enemies, r)
 			}
 		}
 	}
+	// Patrols go round the waypoints in the order of the manifest or the digits
+	for _, w := range s.waypoints {
+		s.route = append(s.route, point{w.x + b.offset, w.y})
+	}
+	for d := '1'; d <= '9' && len(s.waypoints) == 0; d++ {
+		if w, ok := waypoints[d]; ok {
+			s.route = append(s.route, w)
+		}
+	}
+	for _, p := range ps {
+		p.doors = doors
+		p.teleporters = pairTeleporters(teleporters)
+	}
+	for _, e := range s.enemies {
+		e.bind(s)
+	}
 }
 
 func (s stage) plotSubInfo(life int) {
@@ -126,21 +278,40 @@ func (s stage) plotSubInfo(life int) {
 		1: "Life : " + strconv.Itoa(life),
 		2: "PRESS ENTER TO PLAY!",
 		3: "q TO EXIT!"}
+	if s.lesson != nil {
+		textMap[0] = s.lesson.title
+		textMap[4] = s.lesson.hint
+	} else if rule := s.keyRule(); rule != "" {
+		textMap[4] = rule
+	}
 	position := s.height + 1
+	// Under the lines of the other players
+	if s.numPlayers > 1 {
+		position += s.numPlayers - 1
+	}
 	for i := 0; i < len(textMap); i++ {
 		for x, r := range []rune(textMap[i]) {
-			termbox.SetCell(x, position, r, termbox.ColorWhite, termbox.ColorBlack)
+			screen.setCell(x, position, r, termbox.ColorWhite, termbox.ColorBlack)
 		}
 		position++
 	}
 }
 
-func (s stage) start(p *player) error {
+func (s stage) start(ps players) error {
 	eg := new(errgroup.Group)
+	startedAt := time.Now()
+	for _, p := range ps {
+		p.startedAt = startedAt
+	}
+	defer func() {
+		for _, p := range ps {
+			p.playTime = time.Since(p.startedAt)
+		}
+	}()
 
 	eg.Go(func() error {
-		for p.state == continuing {
-			if err := p.control(s); err != nil {
+		for ps.continuing() {
+			if err := ps.control(s); err != nil {
 				return err
 			}
 		}
@@ -148,10 +319,11 @@ func (s stage) start(p *player) error {
 	})
 
 	eg.Go(func() error {
-		for p.state == continuing {
-			if err := s.control(p); err != nil {
+		for ps.continuing() {
+			if err := s.control(ps); err != nil {
 				return err
 			}
+			time.Sleep(s.tickLength(ps))
 		}
 		return nil
 	})
@@ -162,17 +334,97 @@ func (s stage) start(p *player) error {
 	return nil
 }
 
-func (s stage) control(p *player) error {
+func (s *stage) control(ps players) error {
+	boardMu.Lock()
+	defer boardMu.Unlock()
+	for _, p := range ps {
+		if p.energized {
+			p.energized = false
+			for _, e := range s.enemies {
+				e.frighten(s.getFrightenedTime())
+			}
+		}
+		p.countDownPowerUps()
+	}
+	mode := s.currentMode()
+	s.tick++
+	if s.gameMode == survival {
+		s.survive(ps)
+	}
+	// The players move in another goroutine, so collisions are checked against
+	// all the cells each player has passed since the last tick, not only the current one.
+	paths := make([][]point, len(ps))
+	for i, p := range ps {
+		paths[i] = p.trail.take(p.x, p.y)
+	}
 	// Implemented as sequential execution for the following reasons:
 	// - The processing content is light.
 	// - Considering the overlap of enemies makes the implementation complex.
 	for _, e := range s.enemies {
-		e.move(e.think(p))
-		e.hasCaptured(p)
+		// Each enemy goes after the nearest player
+		p := ps.target(e)
+		e.countDown()
+		e.setMode(mode)
+		if !e.update(p) {
+			continue
+		}
+		// Frozen enemies don't move, but they still catch the player who walks into them
+		frozen := p.frozen > 0
+		if !frozen {
+			e.accelerate(s.getGameSpeed(), s.speedMultiplier(e, p))
+		}
+		// A fast enemy may move several cells in a tick
+		for {
+			fromX, fromY := e.getPosition()
+			moved := !frozen && e.isReady() && e.move(e.think(p))
+			for i, q := range ps {
+				if q.state == continuing && hasCollided(e, fromX, fromY, paths[i]) {
+					e.capture(q)
+				}
+			}
+			if !moved || !ps.continuing() {
+				break
+			}
+		}
+		if err := e.failed(); err != nil {
+			return err
+		}
+	}
+	for _, p := range ps {
+		p.plotScore(*s)
 	}
-	if err := termbox.Flush(); err != nil {
+	if err := ps.flush(); err != nil {
 		return err
 	}
-	time.Sleep(s.gameSpeed)
 	return nil
 }
+
+func (s stage) getFrightenedTime() int {
+	if s.frightenedTime == 0 {
+		return defaultFrightenedTime
+	}
+	return s.frightenedTime
+}
+
+// Returns whether enemies scatter or chase at the current tick.
+func (s stage) currentMode() int {
+	t := s.tick
+	for i, ph := range s.phases {
+		if t < ph.ticks || i == len(s.phases)-1 {
+			return ph.mode
+		}
+		t -= ph.ticks
+	}
+	return chase
+}
+
+// Replace the cell an enemy at the cell puts back when it moves off.
+// Returns whether an enemy is at the cell.
+func (s stage) putUnderEnemy(x, y int, c termbox.Cell) bool {
+	for _, e := range s.enemies {
+		if e.putUnder(x, y, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	termbox "github.com/nsf/termbox-go"
+)
+
+// Tiles for puzzles
+const (
+	// Teleporters are paired in the order they appear in the map. Only the players use them, enemies walk over them
+	chTeleporter = '='
+	// One-way tiles can be passed only in the direction of the arrow
+	chOneWayRight = '>'
+	chOneWayLeft  = '<'
+	chOneWayUp    = '^'
+	chOneWayDown  = 'v'
+	// Doors are walls until the player picks up a key
+	chDoor = '#'
+	chKey  = 'K'
+)
+
+// Returns the direction of the one-way tile (0, 0 if the rune is not a one-way tile).
+func oneWayDirection(r rune) (int, int) {
+	switch r {
+	case chOneWayRight:
+		return 1, 0
+	case chOneWayLeft:
+		return -1, 0
+	case chOneWayUp:
+		return 0, -1
+	case chOneWayDown:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+// Whether a move of (dx, dy) from the tile `from` to the tile `to` goes against a one-way tile.
+// A one-way tile can be entered and left only in the direction of the arrow.
+func isOneWayBlocked(from, to rune, dx, dy int) bool {
+	for _, r := range []rune{from, to} {
+		if x, y := oneWayDirection(r); (x != 0 || y != 0) && (x != dx || y != dy) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a jump from a cell to another goes against a one-way tile on any cell it passes.
+func isJumpBlocked(from, to point) bool {
+	prev := from
+	for _, c := range sweep(from, to) {
+		if c == prev {
+			continue
+		}
+		dx, dy := direction(c.x-prev.x, c.y-prev.y)
+		if isOneWayBlocked(getCell(prev.x, prev.y).Ch, getCell(c.x, c.y).Ch, dx, dy) {
+			return true
+		}
+		prev = c
+	}
+	return false
+}
+
+// Pair the teleporters in the order they appear in the map.
+func pairTeleporters(teleporters []point) map[point]point {
+	pairs := map[point]point{}
+	for i := 0; i+1 < len(teleporters); i += 2 {
+		a, b := teleporters[i], teleporters[i+1]
+		pairs[a], pairs[b] = b, a
+	}
+	return pairs
+}
+
+// Open all the doors of the stage.
+func (p *player) openDoors() {
+	for _, d := range p.doors {
+		if isCharDoor(d.x, d.y) {
+			screen.setCell(d.x, d.y, chSpace, termbox.ColorWhite, termbox.ColorBlack)
+		}
+	}
+	p.doors = nil
+}
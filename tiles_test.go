@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+)
+
+const tilesTestMapPath = "files/test/player/tiles.txt"
+
+func TestIsOneWayBlocked(t *testing.T) {
+	cases := map[string]struct {
+		from     rune
+		to       rune
+		dx       int
+		dy       int
+		expected bool
+	}{
+		"enter along the arrow":   {chSpace, chOneWayRight, 1, 0, false},
+		"enter against the arrow": {chSpace, chOneWayRight, -1, 0, true},
+		"enter from the side":     {chSpace, chOneWayDown, 1, 0, true},
+		"leave along the arrow":   {chOneWayUp, chSpace, 0, -1, false},
+		"leave against the arrow": {chOneWayUp, chSpace, 0, 1, true},
+		"no one-way tiles":        {chSpace, chApple, -1, 0, false},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if actual := isOneWayBlocked(tt.from, tt.to, tt.dx, tt.dy); actual != tt.expected {
+				t.Errorf("expected %t but %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTiles(t *testing.T) {
+	cases := map[string]struct {
+		initX     int
+		initY     int
+		inputNum  int
+		moveX     int
+		expectedX int
+		expectedY int
+	}{
+		"teleporter":                    {1, 1, 0, 1, 10, 1},
+		"walk on after the teleporter":  {1, 1, 2, 1, 11, 1},
+		"teleporter on the way back":    {11, 1, 0, -1, 2, 1},
+		"one-way along the arrow":       {4, 1, 0, 1, 5, 1},
+		"one-way against the arrow":     {6, 1, 0, -1, 6, 1},
+		"leave one-way against it":      {5, 1, 0, -1, 5, 1},
+		"one-way to the left":           {8, 2, 2, -1, 6, 2},
+		"door without the key":          {4, 2, 0, -1, 4, 2},
+		"door after picking up the key": {1, 2, 2, 1, 3, 2},
+		"walk through the opened door":  {1, 2, 3, 1, 4, 2},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x:     tt.initX,
+				y:     tt.initY,
+				state: continuing,
+			}
+			_, offset, err := playerActionTestInit(t, tilesTestMapPath, p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			p.inputNum = tt.inputNum
+			p.moveCross(tt.moveX, 0)
+			if expectedX := tt.expectedX + offset; p.x != expectedX || p.y != tt.expectedY {
+				t.Errorf("expected %d %d but %d %d", expectedX, tt.expectedY, p.x, p.y)
+			}
+		})
+	}
+}
+
+// Test jumps can't cross one-way tiles against the arrow.
+func TestJumpOneWay(t *testing.T) {
+	cases := map[string]struct {
+		initX     int
+		initY     int
+		motion    rune
+		expectedX int
+	}{
+		"jump along the arrow":              {3, 1, '$', 11},
+		"jump against the arrow":            {6, 1, '0', 6},
+		"jump over the teleporter":          {6, 1, '$', 11},
+		"jump against the arrow to the end": {5, 2, '$', 5},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x:     tt.initX,
+				y:     tt.initY,
+				state: continuing,
+			}
+			s, offset, err := playerActionTestInit(t, tilesTestMapPath, p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			p.action(tt.motion, s)
+			if expectedX := tt.expectedX + offset; p.x != expectedX || p.y != tt.initY {
+				t.Errorf("expected %d %d but %d %d", expectedX, tt.initY, p.x, p.y)
+			}
+		})
+	}
+}
+
+// Test enemies can't step against one-way tiles.
+func TestEnemyOneWay(t *testing.T) {
+	p, s, err := enemyActionTestInit(t, enemyTestMapPath+"hunter.txt", newEnemyBuilder().defaultHunter())
+	if err != nil {
+		t.Error(err)
+	}
+	e := s.enemies[0].(*enemy)
+	x, y := e.getPosition()
+	p.x, p.y = x, y+3
+	below := getCell(x, y+1)
+	termbox.SetCell(x, y+1, chOneWayUp, termbox.ColorYellow, termbox.ColorBlack)
+	if v := e.eval(p, x, y+1); v != 1000 {
+		t.Errorf("expected %d but %f", 1000, v)
+	}
+	termbox.SetCell(x, y+1, chOneWayDown, termbox.ColorYellow, termbox.ColorBlack)
+	if v := e.eval(p, x, y+1); v == 1000 {
+		t.Errorf("expected the enemy to step on the one-way tile")
+	}
+	termbox.SetCell(x, y+1, below.Ch, below.Fg, below.Bg)
+}
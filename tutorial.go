@@ -0,0 +1,110 @@
+package main
+
+// Motions the player can use in all lessons
+const (
+	lessonKeysCross = "hjkl"
+	lessonKeysWord  = lessonKeysCross + "wbe"
+	lessonKeysLine  = lessonKeysWord + "0$^"
+	lessonKeysJump  = lessonKeysLine + "gG"
+	lessonKeysCount = lessonKeysJump + "123456789"
+)
+
+// Name of the motion with a count, used in the required motions of a lesson
+const countMotion = "count"
+
+// lesson teaches a motion on a small stage.
+type lesson struct {
+	title string
+	hint  string
+	// Motions the player must use to pass the lesson, e.g. "w", "gg" or "count"
+	required []string
+}
+
+func initLessons() []stage {
+	return []stage{
+		{
+			level:       1,
+			mapPath:     "files/tutorial/lesson01.txt",
+			allowedKeys: lessonKeysCross,
+			lesson: &lesson{
+				title:    "Lesson 1: hjkl",
+				hint:     "h: left, j: down, k: up, l: right",
+				required: []string{"h", "j", "k", "l"},
+			},
+		},
+		{
+			level:       2,
+			mapPath:     "files/tutorial/lesson02.txt",
+			allowedKeys: lessonKeysWord,
+			lesson: &lesson{
+				title:    "Lesson 2: w, b and e",
+				hint:     "w: next word, b: previous word, e: end of word",
+				required: []string{"w", "b", "e"},
+			},
+		},
+		{
+			level:       3,
+			mapPath:     "files/tutorial/lesson03.txt",
+			allowedKeys: lessonKeysLine,
+			lesson: &lesson{
+				title:    "Lesson 3: 0, $ and ^",
+				hint:     "0: line start, $: line end, ^: first word",
+				required: []string{"0", "$", "^"},
+			},
+		},
+		{
+			level:       4,
+			mapPath:     "files/tutorial/lesson04.txt",
+			allowedKeys: lessonKeysJump,
+			lesson: &lesson{
+				title:    "Lesson 4: gg and G",
+				hint:     "gg: first line, G: last line",
+				required: []string{"gg", "G"},
+			},
+		},
+		{
+			level:       5,
+			mapPath:     "files/tutorial/lesson05.txt",
+			allowedKeys: lessonKeysCount,
+			lesson: &lesson{
+				title:    "Lesson 5: counts",
+				hint:     "Type a number before a motion, e.g. 10l or 4j",
+				required: []string{countMotion},
+			},
+		},
+	}
+}
+
+// Records the motion typed by the player, so that the lesson can check it was used.
+func (p *player) use(c command) {
+	if p.used == nil {
+		p.used = map[string]bool{}
+	}
+	name := string(c.ch)
+	if c.ch == 'g' {
+		name = "gg"
+	}
+	p.used[name] = true
+	if c.num != 0 {
+		p.used[countMotion] = true
+	}
+}
+
+// Returns the motions of the lesson the player hasn't used yet.
+func (l *lesson) remaining(p *player) []string {
+	if l == nil {
+		return nil
+	}
+	remaining := []string{}
+	for _, m := range l.required {
+		if !p.used[m] {
+			remaining = append(remaining, m)
+		}
+	}
+	return remaining
+}
+
+// Returns whether the player passed the lesson, i.e. used all the motions of it.
+func (l *lesson) passed(p *player) bool {
+	return len(l.remaining(p)) == 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLessonPassed(t *testing.T) {
+	cases := map[string]struct {
+		required  []string
+		inputs    string
+		remaining string
+	}{
+		"all used":      {[]string{"h", "j"}, "hj", ""},
+		"some used":     {[]string{"h", "j", "k"}, "jj", "h k"},
+		"gg":            {[]string{"gg", "G"}, "gG", "gg"},
+		"gg typed":      {[]string{"gg", "G"}, "ggG", ""},
+		"count":         {[]string{countMotion}, "lj", countMotion},
+		"count typed":   {[]string{countMotion}, "2l", ""},
+		"repeated":      {[]string{"l"}, "l.", ""},
+		"not a motion":  {[]string{"x"}, "x", "x"},
+		"nothing typed": {[]string{"w"}, "", "w"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			p := &player{
+				x: 7,
+				y: 5,
+			}
+			s, offset, err := playerActionTestInit(t, playerTestMapPath+"move_cross.txt", p)
+			if err != nil {
+				t.Error(err)
+			}
+			p.x += offset
+			s.lesson = &lesson{required: tt.required}
+			for _, r := range tt.inputs {
+				p.input(r, s)
+			}
+			if remaining := strings.Join(s.lesson.remaining(p), " "); remaining != tt.remaining {
+				t.Errorf("expected %q but %q", tt.remaining, remaining)
+			}
+			if s.lesson.passed(p) != (tt.remaining == "") {
+				t.Errorf("expected %t but %t", tt.remaining == "", s.lesson.passed(p))
+			}
+		})
+	}
+}
+
+// Test the lessons can be cleared with the keys they allow.
+func TestLessons(t *testing.T) {
+	lessons := initLessons()
+	if err := validateFiles(lessons); err != nil {
+		t.Error(err)
+	}
+	for _, s := range lessons {
+		for _, m := range s.lesson.required {
+			key := rune(m[0])
+			if m == countMotion {
+				key = '1'
+			}
+			if !s.isAllowed(key) {
+				t.Errorf("expected %s to be allowed in %s", m, s.lesson.title)
+			}
+		}
+	}
+}